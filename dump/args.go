@@ -0,0 +1,160 @@
+package dump
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Word is a word of the arguments of a call, as printed by the runtime.
+// Since Go 1.17, arguments are printed as
+//
+//	main.f({0xc000010000, 0x1}, 0x2?, ...)
+//
+// with the words of a struct or array argument in braces, a ? after
+// words that may be stale, as they were passed in registers, and ...
+// for words elided.
+type Word struct {
+	Value     uint64
+	Unsure    bool // printed with a trailing ?, as the value may be stale
+	Aggregate bool // word of a struct or array argument, printed in braces
+}
+
+// Occurrence is a call of a goroutine that was passed an object.
+type Occurrence struct {
+	GID   int
+	Stack int  // index of the call in the stacks of the goroutine
+	Stale bool // whether the object was passed in a word that may be stale
+}
+
+// decodeArgs decodes the argument words of a call, and whether some
+// words were elided.
+func decodeArgs(params string) (words []Word, elided bool) {
+	depth := 0
+	for _, field := range strings.Split(params, ",") {
+		field = strings.TrimSpace(field)
+		aggregate := depth > 0 || strings.HasPrefix(field, "{")
+		depth += strings.Count(field, "{") - strings.Count(field, "}")
+		field = strings.Trim(field, "{}")
+		switch {
+		case field == "":
+		case field == "...":
+			elided = true
+		default:
+			w := Word{Aggregate: aggregate}
+			if strings.HasSuffix(field, "?") {
+				w.Unsure = true
+				field = strings.TrimSuffix(field, "?")
+			}
+			if !strings.HasPrefix(field, "0x") {
+				continue
+			}
+			v, err := strconv.ParseUint(field[2:], 16, 64)
+			if err != nil {
+				continue
+			}
+			w.Value = v
+			words = append(words, w)
+		}
+	}
+	return words, elided
+}
+
+// objectArgs maps functions taking an object other than as a receiver
+// to the index of the word holding its address.
+var objectArgs = map[string]int{
+	"runtime.chanrecv":    0,
+	"runtime.chanrecv1":   0,
+	"runtime.chanrecv2":   0,
+	"runtime.chansend":    0,
+	"runtime.chansend1":   0,
+	"runtime.closechan":   0,
+	"runtime.mapaccess1":  1,
+	"runtime.mapaccess2":  1,
+	"runtime.mapassign":   1,
+	"runtime.mapdelete":   1,
+	"runtime.mapiterinit": 1,
+}
+
+// minAddress is the lowest address of an object. Lower words are sizes,
+// flags or nil pointers.
+const minAddress = 0x1000
+
+// Object returns the address of the object a call was passed, which is
+// the receiver of a method with a pointer receiver, or the channel or
+// map of runtime functions operating on them. Closures of a method are
+// not passed its receiver, and words that may be stale are not trusted.
+func (s *Stack) Object() (uint64, bool) {
+	addr, stale, ok := s.PossibleObject()
+	if !ok || stale {
+		return 0, false
+	}
+	return addr, true
+}
+
+// PossibleObject is like Object, but trusts words that may be stale,
+// reporting whether the address was printed in one. Since Go 1.18,
+// receivers passed in registers are mostly printed so, as
+// sync.(*WaitGroup).Wait(0xc0001c2000?), though the receiver of a call
+// still on the stack is seldom stale.
+func (s *Stack) PossibleObject() (addr uint64, stale, ok bool) {
+	i := 0
+	if !s.PointerReceiver || s.Closure != "" {
+		var ok bool
+		if i, ok = objectArgs[s.FuncName]; !ok {
+			return 0, false, false
+		}
+	}
+	if i >= len(s.Args) {
+		return 0, false, false
+	}
+	w := s.Args[i]
+	// The first word of a struct argument is not the address of
+	// the argument.
+	if w.Aggregate || w.Value < minAddress {
+		return 0, false, false
+	}
+	return w.Value, w.Unsure, true
+}
+
+// PassedWords indexes the words of the arguments of every call by value,
+// but for words that may be stale, with the outermost call of each
+// goroutine passed the word. Unlike Addresses, which only holds the
+// objects calls operate on, it finds an object passed as any argument,
+// such as a *sync.WaitGroup.
+func (p *Dump) PassedWords() map[uint64][]Occurrence {
+	index := make(map[uint64][]Occurrence)
+	for _, f := range p.RawFrames {
+		seen := make(map[uint64]bool)
+		for i := len(f.Stacks) - 1; i >= 0; i-- {
+			for _, w := range f.Stacks[i].Args {
+				if w.Unsure || w.Value < minAddress || seen[w.Value] {
+					continue
+				}
+				seen[w.Value] = true
+				index[w.Value] = append(index[w.Value], Occurrence{GID: f.GID, Stack: i})
+			}
+		}
+	}
+	return index
+}
+
+// indexAddresses indexes the objects passed to the calls of f, even in
+// words that may be stale.
+func (p *Dump) indexAddresses(f *Frame) {
+	for i := range f.Stacks {
+		if addr, stale, ok := f.Stacks[i].PossibleObject(); ok {
+			p.Addresses[addr] = append(p.Addresses[addr], Occurrence{GID: f.GID, Stack: i, Stale: stale})
+		}
+	}
+}
+
+// GetOccurrencesByAddr returns the calls passed the object at addr,
+// ordered by goroutine.
+func (p *Dump) GetOccurrencesByAddr(addr uint64) []Occurrence {
+	occurrences := append([]Occurrence(nil), p.Addresses[addr]...)
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].GID < occurrences[j].GID
+	})
+	return occurrences
+}
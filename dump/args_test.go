@@ -0,0 +1,47 @@
+package dump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPossibleObject(t *testing.T) {
+	p := parseFile(t, "go1.21.txt")
+	want := []Occurrence{{GID: 93, Stack: 1, Stale: true}}
+	if got := p.GetOccurrencesByAddr(0xc0001c2000); !reflect.DeepEqual(got, want) {
+		t.Errorf("got occurrences %+v, want %+v", got, want)
+	}
+	s := &p.GetFrameByGID(93).Stacks[1]
+	if addr, ok := s.Object(); ok || addr != 0 {
+		t.Errorf("%s(%s): stale receiver trusted", s.FuncName, s.Params)
+	}
+	p = parseFile(t, "go1.16.txt")
+	want = []Occurrence{{GID: 34, Stack: 1}, {GID: 34, Stack: 3}}
+	if got := p.GetOccurrencesByAddr(0xc0000b6080); !reflect.DeepEqual(got, want) {
+		t.Errorf("got occurrences %+v, want %+v", got, want)
+	}
+}
+
+func TestPassedWords(t *testing.T) {
+	p := NewDump()
+	if err := p.ParseData(`goroutine 7 [select]:
+main.inner(0xc000010000, 0x2)
+	/src/app/main.go:10 +0x85
+main.outer(0xc000010000?, 0xc000020000)
+	/src/app/main.go:20 +0x85
+main.main(0xc000010000)
+	/src/app/main.go:30 +0x85
+`); err != nil {
+		t.Fatal(err)
+	}
+	index := p.PassedWords()
+	for addr, want := range map[uint64][]Occurrence{
+		0xc000010000: {{GID: 7, Stack: 2}},
+		0xc000020000: {{GID: 7, Stack: 1}},
+		0x2:          nil,
+	} {
+		if got := index[addr]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%#x: got occurrences %+v, want %+v", addr, got, want)
+		}
+	}
+}
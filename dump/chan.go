@@ -0,0 +1,137 @@
+package dump
+
+import (
+	"sort"
+	"strings"
+)
+
+// Channel operations goroutines block in.
+const (
+	ChanSend    = "send"
+	ChanReceive = "receive"
+	ChanSelect  = "select"
+)
+
+// ChanWait is a goroutine blocked on a channel operation.
+type ChanWait struct {
+	GID      int
+	Op       string // ChanSend, ChanReceive or ChanSelect
+	Forever  bool   // on a nil channel, or in a select with no cases
+	Addr     uint64 // address of the channel, 0 for selects and nil channels
+	Owner    uint64 // address of the object whose method blocks, 0 if unknown
+	Duration int    // minutes blocked
+	Stack    int    // index of the call blocking in the stacks of the goroutine
+}
+
+// Channel is a channel goroutines are blocked on.
+type Channel struct {
+	Addr      uint64
+	ByOwner   bool // Addr is the owner of the channel, as runtime calls were not printed
+	Senders   []*ChanWait
+	Receivers []*ChanWait
+}
+
+// decodeChanWait returns the channel operation f is blocked in, or nil.
+// The channel is the first argument of runtime.chansend and
+// runtime.chanrecv, but selects pass an array of cases, whose channels
+// are not printed. Runtime calls are not printed either unless
+// GOTRACEBACK is system or more, so the object whose method blocks is
+// recorded too.
+func (f *Frame) decodeChanWait() *ChanWait {
+	w := &ChanWait{GID: f.GID, Duration: f.Duration}
+	switch {
+	case strings.HasPrefix(f.Reason, "chan send"):
+		w.Op = ChanSend
+	case strings.HasPrefix(f.Reason, "chan receive"):
+		w.Op = ChanReceive
+	case strings.HasPrefix(f.Reason, "select"):
+		w.Op = ChanSelect
+	default:
+		return nil
+	}
+	w.Forever = strings.HasSuffix(f.Reason, "(nil chan)") || strings.HasSuffix(f.Reason, "(no cases)")
+	for i := range f.Stacks {
+		s := &f.Stacks[i]
+		if s.ImportPath != "runtime" {
+			w.Stack = i
+			break
+		}
+		if addr, ok := s.Object(); ok && !w.Forever && w.Op != ChanSelect {
+			w.Addr = addr
+		}
+	}
+	if !w.Forever && w.Op != ChanSelect && w.Stack < len(f.Stacks) {
+		w.Owner, _ = f.Stacks[w.Stack].Object()
+	}
+	return w
+}
+
+// ChanWaits returns the goroutines blocked on channel operations,
+// ordered by GID.
+func (p *Dump) ChanWaits() []*ChanWait {
+	var waits []*ChanWait
+	for _, f := range p.RawFrames {
+		if w := f.decodeChanWait(); w != nil {
+			waits = append(waits, w)
+		}
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i].GID < waits[j].GID })
+	return waits
+}
+
+// Channels returns the channels goroutines are blocked sending to or
+// receiving from, the most blocked on first. A channel whose address
+// is not printed is told by its owner, merging the channels of an owner.
+func (p *Dump) Channels() []*Channel {
+	chans := make(map[uint64]*Channel)
+	for _, w := range p.ChanWaits() {
+		addr, byOwner := w.Addr, false
+		if addr == 0 {
+			addr, byOwner = w.Owner, true
+		}
+		if addr == 0 {
+			continue
+		}
+		c := chans[addr]
+		if c == nil {
+			c = &Channel{Addr: addr, ByOwner: byOwner}
+			chans[addr] = c
+		}
+		if w.Op == ChanSend {
+			c.Senders = append(c.Senders, w)
+		} else {
+			c.Receivers = append(c.Receivers, w)
+		}
+	}
+	channels := make([]*Channel, 0, len(chans))
+	for _, c := range chans {
+		channels = append(channels, c)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		ni := len(channels[i].Senders) + len(channels[i].Receivers)
+		nj := len(channels[j].Senders) + len(channels[j].Receivers)
+		if ni != nj {
+			return ni > nj
+		}
+		return channels[i].Addr < channels[j].Addr
+	})
+	return channels
+}
+
+// Orphaned reports whether only senders or only receivers are blocked on
+// c, all of them for at least minutes, so that no goroutine is likely to
+// unblock them: the channel probably leaks them. Channels told by their
+// owner may be several, so they are never reported.
+func (c *Channel) Orphaned(minutes int) bool {
+	if c.ByOwner || (len(c.Senders) == 0) == (len(c.Receivers) == 0) {
+		return false
+	}
+	for _, waits := range [][]*ChanWait{c.Senders, c.Receivers} {
+		for _, w := range waits {
+			if w.Duration < minutes {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package dump
+
+import "testing"
+
+func TestOrphaned(t *testing.T) {
+	const dump = `goroutine 5 [chan send, 30 minutes]:
+runtime.chansend1(0xc000100000, 0xc000050f58)
+	/usr/local/go/src/runtime/chan.go:145 +0x1d
+main.(*Worker).produce(0xc000110000)
+	/src/app/main.go:20 +0x3c
+
+goroutine 6 [chan send, 30 minutes]:
+main.(*Worker).produce(0xc000110000)
+	/src/app/main.go:20 +0x3c
+
+goroutine 7 [chan receive, 30 minutes]:
+main.(*Worker).consume(0xc000110000)
+	/src/app/main.go:30 +0x3c
+
+goroutine 8 [chan receive, 30 minutes]:
+main.(*Watcher).loop(0xc000120000)
+	/src/app/main.go:40 +0x3c
+
+goroutine 9 [select, 30 minutes]:
+main.(*Watcher).run(0xc000120000)
+	/src/app/main.go:50 +0x3c
+`
+	p := NewDump()
+	if err := p.ParseData(dump); err != nil {
+		t.Fatal(err)
+	}
+	channels := make(map[uint64]*Channel)
+	for _, c := range p.Channels() {
+		channels[c.Addr] = c
+	}
+	if len(channels) != 3 {
+		t.Fatalf("got %d channels, want 3", len(channels))
+	}
+	for _, tc := range []struct {
+		addr    uint64
+		byOwner bool
+		minutes int
+		want    bool
+	}{
+		{0xc000100000, false, 10, true},
+		{0xc000100000, false, 60, false},
+		// Told by their owner, the channels may be several.
+		{0xc000110000, true, 10, false},
+		{0xc000120000, true, 10, false},
+	} {
+		c := channels[tc.addr]
+		if c == nil {
+			t.Errorf("no channel %#x", tc.addr)
+			continue
+		}
+		if c.ByOwner != tc.byOwner {
+			t.Errorf("channel %#x: got by owner %v, want %v", tc.addr, c.ByOwner, tc.byOwner)
+		}
+		if got := c.Orphaned(tc.minutes); got != tc.want {
+			t.Errorf("channel %#x: got orphaned for %d minutes %v, want %v", tc.addr, tc.minutes, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dump
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Cluster is the goroutine groups blocked for the same reason whose
+// stacks are similar, such as a handler reached through slightly
+// different chains of middleware.
+type Cluster struct {
+	Representative *TrimedFrame   // largest group, which the others are similar to
+	Groups         []*TrimedFrame // groups of the cluster, the largest first
+	Count          int            // goroutines of the cluster
+	Variants       []Variant      // calls the stacks of the groups differ from the representative by
+}
+
+// Variant is a call some stacks of a cluster have and its representative
+// lacks, or the reverse.
+type Variant struct {
+	FuncName string
+	Missing  bool // whether the stacks lack the call the representative has
+	Count    int  // goroutines whose stacks differ by the call
+}
+
+// CheckSimilarity checks that a similarity threshold is a fraction.
+func CheckSimilarity(threshold float64) error {
+	if threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("similarity %v is not in (0, 1]", threshold)
+	}
+	return nil
+}
+
+// Similarity returns the similarity of the stacks of f and f2, from 0 to
+// 1 for identical ones: 1 less the edit distance between their sequences
+// of functions, over the length of the longer one.
+func Similarity(f, f2 *Frame) float64 {
+	a, b := f.Stacks, f2.Stacks
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	if n == 0 {
+		return 1
+	}
+	// Levenshtein distance, a row at a time.
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1].FuncName == b[j-1].FuncName {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(n)
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// Clusters clusters groups whose stacks are at least threshold similar,
+// the most goroutines first. Groups are taken the largest first, each
+// joining the first cluster whose representative it is similar enough
+// to, or else starting one.
+func Clusters(groups map[string]TrimedFrame, threshold float64) []*Cluster {
+	keys := sortedKeys(groups)
+	sort.SliceStable(keys, func(i, j int) bool { return groups[keys[i]].Count > groups[keys[j]].Count })
+	var clusters []*Cluster
+	for _, key := range keys {
+		tf := groups[key]
+		var c *Cluster
+		for _, candidate := range clusters {
+			r := candidate.Representative
+			if r.Reason == tf.Reason && Similarity(&r.Frame, &tf.Frame) >= threshold {
+				c = candidate
+				break
+			}
+		}
+		if c == nil {
+			c = &Cluster{Representative: &tf}
+			clusters = append(clusters, c)
+		}
+		c.Groups = append(c.Groups, &tf)
+		c.Count += tf.Count
+	}
+	for _, c := range clusters {
+		c.Variants = c.variants()
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Count > clusters[j].Count })
+	return clusters
+}
+
+// variants returns the calls the stacks of the groups of c differ from
+// its representative by, the most goroutines first.
+func (c *Cluster) variants() []Variant {
+	funcs := func(tf *TrimedFrame) map[string]bool {
+		m := make(map[string]bool)
+		for _, s := range tf.Stacks {
+			m[s.FuncName] = true
+		}
+		return m
+	}
+	rep := funcs(c.Representative)
+	var variants []Variant
+	index := make(map[Variant]int)
+	add := func(name string, missing bool, count int) {
+		v := Variant{FuncName: name, Missing: missing}
+		i, ok := index[v]
+		if !ok {
+			i = len(variants)
+			index[v] = i
+			variants = append(variants, v)
+		}
+		variants[i].Count += count
+	}
+	for _, g := range c.Groups[1:] {
+		calls := funcs(g)
+		seen := make(map[string]bool)
+		for _, s := range g.Stacks {
+			if !rep[s.FuncName] && !seen[s.FuncName] {
+				seen[s.FuncName] = true
+				add(s.FuncName, false, g.Count)
+			}
+		}
+		for _, s := range c.Representative.Stacks {
+			if !calls[s.FuncName] && !seen[s.FuncName] {
+				seen[s.FuncName] = true
+				add(s.FuncName, true, g.Count)
+			}
+		}
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Count > variants[j].Count })
+	return variants
+}
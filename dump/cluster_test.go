@@ -0,0 +1,97 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goroutineText returns the text of goroutine gid, blocked for reason in
+// calls, the innermost first.
+func goroutineText(gid int, reason string, calls ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "goroutine %d [%s]:\n", gid, reason)
+	for i, call := range calls {
+		fmt.Fprintf(&b, "%s(...)\n\t/src/app/server.go:%d\n", call, 10*(i+1))
+	}
+	return b.String()
+}
+
+func TestClusters(t *testing.T) {
+	const (
+		handle = "main.handle"
+		logs   = "example.com/app/middleware.Logging.func1"
+		auth   = "example.com/app/middleware.Auth.func1"
+		gzip   = "example.com/app/middleware.Gzip.func1"
+		serve  = "net/http.(*conn).serve"
+	)
+	dumps := []string{
+		// Requests through the usual middleware.
+		goroutineText(1, "select", handle, logs, auth, serve),
+		goroutineText(2, "select", handle, logs, auth, serve),
+		goroutineText(3, "select", handle, logs, auth, serve),
+		// Compressed requests, through another middleware.
+		goroutineText(4, "select", handle, logs, gzip, serve),
+		goroutineText(5, "select", handle, logs, gzip, serve),
+		// Unauthenticated requests.
+		goroutineText(6, "select", handle, logs, serve),
+		// The same stack blocked for another reason.
+		goroutineText(7, "chan receive", handle, logs, auth, serve),
+	}
+	p := NewDump()
+	if err := p.ParseData(strings.Join(dumps, "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.TrimedFrames) != 4 {
+		t.Fatalf("got %d groups, want 4", len(p.TrimedFrames))
+	}
+
+	group := func(gid int) *Frame {
+		for _, tf := range p.TrimedFrames {
+			for _, h := range tf.Heads {
+				if h.GID == gid {
+					return &tf.Frame
+				}
+			}
+		}
+		t.Fatalf("no goroutine %d", gid)
+		return nil
+	}
+	for _, tc := range []struct {
+		gid, gid2 int
+		want      float64
+	}{
+		{1, 1, 1},
+		{1, 7, 1},
+		{1, 4, 0.75},
+		{1, 6, 0.75},
+		{4, 6, 0.75},
+	} {
+		if got := Similarity(group(tc.gid), group(tc.gid2)); got != tc.want {
+			t.Errorf("got similarity %v of goroutines %d and %d, want %v", got, tc.gid, tc.gid2, tc.want)
+		}
+	}
+
+	clusters := Clusters(p.TrimedFrames, 0.75)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2", len(clusters))
+	}
+	c := clusters[0]
+	if c.Count != 6 || len(c.Groups) != 3 || c.Representative.Count != 3 || c.Representative.Reason != "select" {
+		t.Errorf("got cluster of %d goroutines in %d groups, represented by %d, want 6 in 3 by 3", c.Count, len(c.Groups), c.Representative.Count)
+	}
+	want := []Variant{{FuncName: auth, Missing: true, Count: 3}, {FuncName: gzip, Count: 2}}
+	if !reflect.DeepEqual(c.Variants, want) {
+		t.Errorf("got variants %+v, want %+v", c.Variants, want)
+	}
+	if c := clusters[1]; c.Count != 1 || c.Representative.Reason != "chan receive" || len(c.Variants) != 0 {
+		t.Errorf("got cluster of %d goroutines blocked for %s, want goroutine 7 alone", c.Count, c.Representative.Reason)
+	}
+
+	// Above the similarity of the middleware variants, each group is a
+	// cluster of its own.
+	if clusters := Clusters(p.TrimedFrames, 0.8); len(clusters) != 4 {
+		t.Errorf("got %d clusters at similarity 0.8, want 4", len(clusters))
+	}
+}
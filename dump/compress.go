@@ -0,0 +1,86 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompress returns a reader of the decompressed content of r if it is
+// compressed with gzip, bzip2 or zstd, recognized by its magic bytes, or
+// a reader of r itself otherwise. Closing the reader releases the
+// decompressors but does not close r.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	return decompress(r)
+}
+
+// decompressor reads the content of a possibly compressed stream.
+type decompressor struct {
+	*bufio.Reader
+	closers []io.Closer
+}
+
+func (d *decompressor) Close() error {
+	for _, c := range d.closers {
+		c.Close()
+	}
+	d.closers = nil
+	return nil
+}
+
+// decompress undoes the compression of r, including nested
+// compression, as left by compressing a file twice.
+func decompress(r io.Reader) (*decompressor, error) {
+	d := &decompressor{}
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, readerSize)
+	}
+	for {
+		magic, _ := br.Peek(len(zstdMagic))
+		var zr io.Reader
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			r, err := gzip.NewReader(br)
+			if err != nil {
+				d.Close()
+				return nil, fmt.Errorf("decompressing gzip: %v", err)
+			}
+			d.closers = append(d.closers, r)
+			zr = r
+		case isBzip2(magic):
+			zr = bzip2.NewReader(br)
+		case bytes.HasPrefix(magic, zstdMagic):
+			r, err := zstd.NewReader(br)
+			if err != nil {
+				d.Close()
+				return nil, fmt.Errorf("decompressing zstd: %v", err)
+			}
+			rc := r.IOReadCloser()
+			d.closers = append(d.closers, rc)
+			zr = rc
+		default:
+			d.Reader = br
+			return d, nil
+		}
+		br = bufio.NewReaderSize(zr, readerSize)
+	}
+}
+
+// isBzip2 reports whether magic starts a bzip2 stream, whose signature
+// is followed by the block size, from 1 to 9.
+func isBzip2(magic []byte) bool {
+	return len(magic) > len(bzip2Magic) && bytes.HasPrefix(magic, bzip2Magic) &&
+		magic[len(bzip2Magic)] >= '1' && magic[len(bzip2Magic)] <= '9'
+}
@@ -0,0 +1,119 @@
+package dump
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zstded(t *testing.T, data []byte) []byte {
+	t.Helper()
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	return w.EncodeAll(data, nil)
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecompress(t *testing.T) {
+	text := readTestdata(t, "go1.21.txt")
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"plain", text},
+		{"gzip", gzipped(t, text)},
+		// The standard library has no bzip2 compressor, so the stream
+		// was compressed by bzip2(1).
+		{"bzip2", readTestdata(t, "go1.21.txt.bz2")},
+		{"zstd", zstded(t, text)},
+		{"gzip of zstd", gzipped(t, zstded(t, text))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, text) {
+				t.Errorf("got %d bytes, want the %d bytes of the dump", len(got), len(text))
+			}
+
+			p := NewDump()
+			if err := p.Parse(bytes.NewReader(tc.data)); err != nil {
+				t.Fatal(err)
+			}
+			if want := parseFile(t, "go1.21.txt"); !reflect.DeepEqual(p.RawFrames, want.RawFrames) {
+				t.Errorf("got goroutines %+v, want %+v", p.RawFrames, want.RawFrames)
+			}
+		})
+	}
+}
+
+func TestDecompressTruncated(t *testing.T) {
+	text := readTestdata(t, "go1.21.txt")
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"gzip", gzipped(t, text)},
+		{"bzip2", readTestdata(t, "go1.21.txt.bz2")},
+		{"zstd", zstded(t, text)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(tc.data[:len(tc.data)/2]))
+			if err != nil {
+				return
+			}
+			defer r.Close()
+			if _, err := ioutil.ReadAll(r); err == nil {
+				t.Error("read a truncated stream without error")
+			}
+		})
+	}
+}
+
+func TestDecompressLookalike(t *testing.T) {
+	// A dump starting like a bzip2 signature, without a block size.
+	text := []byte("BZh, not compressed\n")
+	r, err := Decompress(bytes.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(got, text) {
+		t.Errorf("got %q, %v, want %q", got, err, text)
+	}
+}
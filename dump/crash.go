@@ -0,0 +1,110 @@
+package dump
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Crash is what made a process print its goroutines, as told by the
+// lines preceding them:
+//
+//	panic: runtime error: invalid memory address or nil pointer dereference
+//	[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x48f0d6]
+//
+//	fatal error: concurrent map writes
+//
+//	SIGABRT: abort
+//	PC=0x46e8e1 m=0 sigcode=0
+type Crash struct {
+	Kind    string // "panic", "fatal error" or "signal"
+	Message string // message of the panic or fatal error, or description of the signal
+	Signal  string // signal received, such as SIGSEGV, if any
+	Code    string // signal code, if printed
+	PC      uint64 // faulting program counter, 0 if not printed
+	Addr    uint64 // faulting address, 0 if not printed
+	HasAddr bool   // whether Addr was printed, as it is often 0
+	GID     int    // goroutine that crashed, 0 if none was running
+}
+
+// Crash kinds.
+const (
+	CrashPanic  = "panic"
+	CrashFatal  = "fatal error"
+	CrashSignal = "signal"
+)
+
+// SIGSEGV: segmentation violation
+var crashSignalRE = regexp.MustCompile(`^(SIG[A-Z0-9]+): (.*)$`)
+
+// [signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x48f0d6]
+var crashSignalInfoRE = regexp.MustCompile(`^\[signal (SIG[A-Z0-9]+): (.*?)(?: code=(\S+))?(?: addr=(0x[0-9a-f]+))?(?: pc=(0x[0-9a-f]+))?\]$`)
+
+// PC=0x46e8e1 m=0 sigcode=0 addr=0x0
+var crashPCRE = regexp.MustCompile(`^PC=(0x[0-9a-f]+) m=\d+ sigcode=(\S+)(?: addr=(0x[0-9a-f]+))?`)
+
+// isCrash reports whether line starts the report of a crash.
+func isCrash(line string) bool {
+	return strings.HasPrefix(line, "panic: ") ||
+		strings.HasPrefix(line, "fatal error: ") ||
+		strings.HasPrefix(line, "unexpected fault address ") ||
+		crashSignalRE.MatchString(line)
+}
+
+// decode decodes the lines of the report of a crash into c. A report may
+// span several blocks of lines, such as a fault address followed by
+// the fatal error it caused.
+func (c *Crash) decode(lines []string) {
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "panic: "):
+			c.setKind(CrashPanic, strings.TrimPrefix(line, "panic: "))
+		case strings.HasPrefix(line, "fatal error: "):
+			c.setKind(CrashFatal, strings.TrimPrefix(line, "fatal error: "))
+		case strings.HasPrefix(line, "unexpected fault address "):
+			c.setAddr(strings.TrimPrefix(line, "unexpected fault address "))
+		case crashSignalInfoRE.MatchString(line):
+			m := crashSignalInfoRE.FindStringSubmatch(line)
+			c.Signal, c.Code = m[1], m[3]
+			c.setAddr(m[4])
+			c.PC, _ = parseHex(m[5])
+		case crashPCRE.MatchString(line):
+			m := crashPCRE.FindStringSubmatch(line)
+			c.PC, _ = parseHex(m[1])
+			c.Code = m[2]
+			c.setAddr(m[3])
+		case crashSignalRE.MatchString(line) && c.Kind == "":
+			m := crashSignalRE.FindStringSubmatch(line)
+			c.Kind, c.Signal, c.Message = CrashSignal, m[1], m[2]
+		case strings.TrimSpace(line) != "":
+			// Panics raised while panicking, and other notes of
+			// the runtime, such as a signal arrived during cgo
+			// execution.
+			c.Message += "\n" + strings.TrimSpace(line)
+		}
+	}
+}
+
+// setKind sets the kind of the crash, unless it is already known, as
+// the first panic or fatal error printed is the one reported.
+func (c *Crash) setKind(kind, message string) {
+	if c.Kind != "" && c.Kind != CrashSignal {
+		c.Message += "\n" + kind + ": " + message
+		return
+	}
+	c.Kind, c.Message = kind, message
+}
+
+func (c *Crash) setAddr(s string) {
+	if addr, ok := parseHex(s); ok {
+		c.Addr, c.HasAddr = addr, true
+	}
+}
+
+func parseHex(s string) (uint64, bool) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	return v, err == nil
+}
@@ -0,0 +1,49 @@
+package dump
+
+import "testing"
+
+func TestParseCrash(t *testing.T) {
+	for _, tc := range []struct {
+		file  string
+		crash Crash
+	}{
+		{"panic.txt", Crash{
+			Kind:    CrashPanic,
+			Message: "runtime error: invalid memory address or nil pointer dereference",
+			Signal:  "SIGSEGV",
+			Code:    "0x1",
+			PC:      0x48324d,
+			Addr:    0x0,
+			HasAddr: true,
+			GID:     1,
+		}},
+		{"concurrent-map-writes.txt", Crash{
+			Kind:    CrashFatal,
+			Message: "concurrent map writes",
+			GID:     10,
+		}},
+		{"sigsegv.txt", Crash{
+			Kind:    CrashSignal,
+			Message: "segmentation violation\nsignal arrived during cgo execution",
+			Signal:  "SIGSEGV",
+			Code:    "1",
+			PC:      0x7f3a2c1b4e2d,
+			Addr:    0x10,
+			HasAddr: true,
+			GID:     7,
+		}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			p := parseFile(t, tc.file)
+			if p.Crash == nil {
+				t.Fatal("no crash")
+			}
+			if *p.Crash != tc.crash {
+				t.Errorf("got %+v, want %+v", *p.Crash, tc.crash)
+			}
+		})
+	}
+	if p := parseFile(t, "go1.21.txt"); p.Crash != nil {
+		t.Errorf("go1.21.txt: got crash %+v, want none", *p.Crash)
+	}
+}
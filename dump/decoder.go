@@ -0,0 +1,239 @@
+package dump
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// readerSize is the size of the buffer used to read a dump.
+	readerSize = 64 << 10
+
+	// maxLineSize bounds a single line of a dump. The remainder of a
+	// longer line is discarded rather than buffered.
+	maxLineSize = 1 << 20
+)
+
+// textDecoder reads a goroutine dump line by line. Only the lines of the
+// goroutine being decoded are kept in memory, so the memory needed to
+// decode a dump is proportional to its largest goroutine rather than
+// to the size of the dump.
+//
+// A file may hold several dumps of a process taken over time, which
+// the decoder tells apart as snapshots.
+type textDecoder struct {
+	r     *bufio.Reader
+	lines []string // lines of the goroutine being decoded
+	read  int      // number of lines read so far
+	err   error    // sticky error returned once lines are exhausted
+
+	truncated int // lines cut at maxLineSize since last taken
+
+	log       logUnwrapper // unwraps lines of the dump from log lines
+	unwrapped []string     // lines unwrapped but not yet returned
+
+	source  Source       // snapshot of the goroutine last returned
+	crash   *Crash       // crash reported by the current snapshot
+	pending *Source      // preamble of the snapshot about to start
+	crashed *Crash       // crash reported ahead of the snapshot about to start
+	gids    map[int]bool // goroutines of the current snapshot
+	frames  int          // goroutines returned in the current snapshot
+}
+
+func newTextDecoder(r io.Reader) *textDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, readerSize)
+	}
+	return &textDecoder{r: br, gids: make(map[int]bool)}
+}
+
+// readLine returns the next line of the dump without its line
+// terminator, unwrapped from the log line holding it.
+func (d *textDecoder) readLine() (string, error) {
+	for len(d.unwrapped) == 0 {
+		line, err := d.readLogLine()
+		if err != nil {
+			return "", err
+		}
+		d.unwrapped = d.log.unwrap(line)
+	}
+	line := d.unwrapped[0]
+	d.unwrapped = d.unwrapped[1:]
+	return line, nil
+}
+
+// readLogLine returns the next line read without its line terminator.
+func (d *textDecoder) readLogLine() (string, error) {
+	if d.err != nil {
+		return "", d.err
+	}
+	var line []byte
+	truncated := false
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		// Once a line is cut, the rest of it up to the newline is
+		// discarded, so that its head is not spliced with its tail.
+		if !truncated && len(line)+len(chunk) <= maxLineSize {
+			line = append(line, chunk...)
+		} else if !truncated {
+			truncated = true
+			d.truncated++
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			d.err = err
+			if len(line) == 0 {
+				return "", err
+			}
+		}
+		break
+	}
+	d.read++
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// next returns the next goroutine of the dump, and the number of
+// goroutines it stands for if it is a record of an aggregated profile,
+// or 0 otherwise. It returns io.EOF once the dump is exhausted.
+func (d *textDecoder) next() (*Frame, int, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if err == io.EOF {
+				if f, count := d.flush(); f != nil {
+					return f, count, nil
+				}
+			}
+			return nil, 0, err
+		}
+		switch {
+		case isPreamble(line):
+			f, count := d.flush()
+			d.pending = decodePreamble(line)
+			if f != nil {
+				return f, count, nil
+			}
+		case isHead(line), isRecord(line), isCrash(line):
+			// A goroutine normally ends with an empty line, but be
+			// lenient with dumps that lost them.
+			f, count := d.flush()
+			d.lines = append(d.lines, line)
+			if f != nil {
+				return f, count, nil
+			}
+		case strings.TrimSpace(line) == "":
+			if f, count := d.flush(); f != nil {
+				return f, count, nil
+			}
+		case len(d.lines) > 0:
+			d.lines = append(d.lines, line)
+		}
+	}
+}
+
+// flush decodes the buffered lines into a frame, or returns nil if no
+// goroutine is being decoded.
+func (d *textDecoder) flush() (frame *Frame, count int) {
+	if len(d.lines) == 0 {
+		return nil, 0
+	}
+	if isCrash(d.lines[0]) {
+		if d.crashed == nil {
+			d.crashed = &Crash{}
+		}
+		d.crashed.decode(d.lines)
+		d.lines = d.lines[:0]
+		return nil, 0
+	}
+	frame = &Frame{}
+	if isRecord(d.lines[0]) {
+		count = frame.decodeRecord(d.lines)
+	} else {
+		frame.decodeHead(d.lines[0])
+		frame.decodeBody(d.lines[1:])
+	}
+	d.lines = d.lines[:0]
+	d.snapshot(frame)
+	return frame, count
+}
+
+// snapshot assigns frame to a snapshot. A snapshot starts at a preamble
+// or a crash, or at a goroutine already seen in the current snapshot,
+// as dumps appended to the same log do not always have a preamble.
+func (d *textDecoder) snapshot(frame *Frame) {
+	if d.pending != nil || d.crashed != nil || d.gids[frame.GID] {
+		index := d.source.Index
+		if d.frames > 0 {
+			index++
+			d.gids = make(map[int]bool)
+			d.frames = 0
+		}
+		if d.pending != nil {
+			d.source = *d.pending
+		} else {
+			// Without a preamble, the snapshot is likely of the
+			// same process, taken at an unknown time.
+			d.source.Time = time.Time{}
+		}
+		d.source.Index = index
+		d.crash = d.crashed
+		d.pending, d.crashed = nil, nil
+	}
+	// The goroutine that crashed is printed first, in a syscall if
+	// it crashed in C code.
+	if d.crash != nil && d.frames == 0 && frame.GID > 0 && (frame.Reason == "running" ||
+		frame.Reason == "syscall" && strings.Contains(d.crash.Message, "during cgo execution")) {
+		d.crash.GID = frame.GID
+	}
+	if frame.GID > 0 {
+		d.gids[frame.GID] = true
+	}
+	d.frames++
+}
+
+// isHead reports whether line is the header of a goroutine.
+func isHead(line string) bool {
+	return strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(line, "]:")
+}
+
+// dockerd pid: 2431, time: 2021-12-01T084212Z
+var preambleRE = regexp.MustCompile(`^(\S+) pid: (\d+), time: (\S+)$`)
+
+// isPreamble reports whether line starts a snapshot: either the
+// preamble printed by daemons such as dockerd when dumping their
+// goroutines, or the header of an aggregated goroutine profile.
+func isPreamble(line string) bool {
+	return strings.HasPrefix(line, "goroutine profile: total ") || preambleRE.MatchString(line)
+}
+
+// preambleTimeLayouts are the layouts of the time of a preamble.
+var preambleTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T150405Z0700",
+	"2006-01-02T150405.999999999Z0700",
+}
+
+// decodePreamble returns the snapshot started by a preamble.
+func decodePreamble(line string) *Source {
+	src := &Source{}
+	m := preambleRE.FindStringSubmatch(line)
+	if m == nil {
+		return src
+	}
+	src.Process = m[1]
+	src.PID, _ = strconv.Atoi(m[2])
+	for _, layout := range preambleTimeLayouts {
+		if t, err := time.Parse(layout, m[3]); err == nil {
+			src.Time = t
+			break
+		}
+	}
+	return src
+}
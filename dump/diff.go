@@ -0,0 +1,131 @@
+package dump
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signature returns the stack signature of f: its reason, the functions
+// and lines of its calls, its creator and its labels, which goroutines
+// are grouped into TrimedFrames by. Unlike Location, it leaves out the PC
+// offsets, so that it matches across dumps of rebuilt binaries whose
+// sources did not change.
+func (f *Frame) Signature() string {
+	var b strings.Builder
+	b.WriteString(f.Reason)
+	for _, s := range f.Stacks {
+		b.WriteString("\n")
+		b.WriteString(s.FuncName)
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(s.Line))
+	}
+	if f.Creator != nil {
+		b.WriteString("\ncreated by ")
+		b.WriteString(f.Creator.FuncName)
+	}
+	keys := make([]string, 0, len(f.Labels))
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("\n")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(f.Labels[k])
+	}
+	return b.String()
+}
+
+// Minutes returns the longest wait of the goroutines of tf.
+func (tf *TrimedFrame) Minutes() int {
+	minutes := tf.Duration
+	for _, h := range tf.Heads {
+		if h.Duration > minutes {
+			minutes = h.Duration
+		}
+	}
+	return minutes
+}
+
+// GroupDiff is the change between two dumps of the goroutines sharing a
+// stack signature.
+type GroupDiff struct {
+	Signature   string
+	Base        *TrimedFrame // group in the base dump, nil if new
+	Target      *TrimedFrame // group in the dump compared, nil if vanished
+	BaseCount   int
+	Count       int
+	BaseMinutes int // longest wait in the base dump
+	Minutes     int // longest wait in the dump compared
+}
+
+// Growth returns the change of the number of goroutines of the group.
+func (d *GroupDiff) Growth() int {
+	return d.Count - d.BaseCount
+}
+
+// Diff matches the goroutine groups of p with those of base by stack
+// signature, and returns the change of each group, the most grown first.
+// Groups of either dump sharing a signature are merged.
+func Diff(base, p *Dump) []*GroupDiff {
+	groups := make(map[string]*GroupDiff)
+	group := func(tf *TrimedFrame) *GroupDiff {
+		sig := tf.Signature()
+		d := groups[sig]
+		if d == nil {
+			d = &GroupDiff{Signature: sig}
+			groups[sig] = d
+		}
+		return d
+	}
+	for _, key := range sortedKeys(base.TrimedFrames) {
+		tf := base.TrimedFrames[key]
+		d := group(&tf)
+		if d.Base == nil {
+			d.Base = &tf
+		}
+		d.BaseCount += tf.Count
+		if m := tf.Minutes(); m > d.BaseMinutes {
+			d.BaseMinutes = m
+		}
+	}
+	for _, key := range sortedKeys(p.TrimedFrames) {
+		tf := p.TrimedFrames[key]
+		d := group(&tf)
+		if d.Target == nil {
+			d.Target = &tf
+		}
+		d.Count += tf.Count
+		if m := tf.Minutes(); m > d.Minutes {
+			d.Minutes = m
+		}
+	}
+
+	diffs := make([]*GroupDiff, 0, len(groups))
+	for _, d := range groups {
+		diffs = append(diffs, d)
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if gi, gj := diffs[i].Growth(), diffs[j].Growth(); gi != gj {
+			return gi > gj
+		}
+		if mi, mj := diffs[i].Minutes-diffs[i].BaseMinutes, diffs[j].Minutes-diffs[j].BaseMinutes; mi != mj {
+			return mi > mj
+		}
+		return diffs[i].Signature < diffs[j].Signature
+	})
+	return diffs
+}
+
+// sortedKeys returns the keys of the groups of a dump in order, so that
+// the first group of merged ones does not vary between runs.
+func sortedKeys(frames map[string]TrimedFrame) []string {
+	keys := make([]string, 0, len(frames))
+	for k := range frames {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
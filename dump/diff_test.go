@@ -0,0 +1,79 @@
+package dump
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	const worker = `goroutine %d [chan receive, %d minutes]:
+main.worker(0xc000010000)
+	/src/app/main.go:12 +0x%x
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+`
+	const poller = `goroutine 9 [sleep]:
+time.Sleep(0x3b9aca00)
+	/usr/local/go/src/runtime/time.go:195 +0x125
+main.poll()
+	/src/app/poll.go:20 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:9 +0x5a
+`
+	const handler = `goroutine 40 [IO wait, 2 minutes]:
+internal/poll.runtime_pollWait(0x7f2c5c4f8e28, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:343 +0x85
+main.handle(0xc000020000)
+	/src/app/server.go:31 +0x65
+created by main.serve in goroutine 1
+	/src/app/server.go:24 +0x7a
+`
+	parse := func(text string) *Dump {
+		t.Helper()
+		p := NewDump()
+		if err := p.ParseData(text); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	// The workers of the rebuilt binary have other goroutine IDs and PC
+	// offsets, and are still matched with those of the base.
+	base := parse(fmt.Sprintf(worker, 5, 3, 0x45) + "\n" + fmt.Sprintf(worker, 6, 3, 0x45) + "\n" + poller)
+	p := parse(fmt.Sprintf(worker, 21, 5, 0x51) + "\n" + fmt.Sprintf(worker, 22, 5, 0x51) + "\n" +
+		fmt.Sprintf(worker, 23, 1, 0x51) + "\n" + fmt.Sprintf(worker, 24, 1, 0x51) + "\n" + handler)
+
+	diffs := Diff(base, p)
+	want := []struct {
+		fn                    string
+		baseCount, count      int
+		baseMinutes, minutes  int
+		appeared, disappeared bool
+	}{
+		{"main.worker", 2, 4, 3, 5, false, false},
+		{"main.handle", 0, 1, 0, 2, true, false},
+		{"main.poll", 1, 0, 0, 0, false, true},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(diffs), len(want))
+	}
+	for i, w := range want {
+		d := diffs[i]
+		tf := d.Target
+		if tf == nil {
+			tf = d.Base
+		}
+		if fn := tf.Stacks[len(tf.Stacks)-1].FuncName; fn != w.fn {
+			t.Errorf("group %d: got %s, want %s", i, fn, w.fn)
+			continue
+		}
+		if d.BaseCount != w.baseCount || d.Count != w.count || d.Growth() != w.count-w.baseCount {
+			t.Errorf("%s: got %d -> %d goroutines, want %d -> %d", w.fn, d.BaseCount, d.Count, w.baseCount, w.count)
+		}
+		if d.BaseMinutes != w.baseMinutes || d.Minutes != w.minutes {
+			t.Errorf("%s: got longest wait %d -> %d minutes, want %d -> %d", w.fn, d.BaseMinutes, d.Minutes, w.baseMinutes, w.minutes)
+		}
+		if (d.Base == nil) != w.appeared || (d.Target == nil) != w.disappeared {
+			t.Errorf("%s: got base %v and target %v", w.fn, d.Base != nil, d.Target != nil)
+		}
+	}
+}
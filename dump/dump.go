@@ -1,35 +1,65 @@
 package dump
 
 import (
+	"bytes"
 	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Head struct {
 	GID      int
 	Duration int
+	Instance int // instance of a merged dump the goroutine is on, from 1, 0 if not merged
 }
 
 type Stack struct {
 	Location string
 	FuncName string
 	Params   string
+
+	// Location and FuncName decoded once parsed, so that analyses need
+	// not decode them again.
+	File            string
+	Line            int
+	PCOffset        uint64 // offset of the PC in the function, 0 if not printed
+	ImportPath      string // import path of the package, without any vendor prefix
+	Package         string // package name, as guessed from ImportPath
+	Receiver        string // receiver type of a method, without type arguments
+	PointerReceiver bool
+	Method          string // name of the function or method
+	Closure         string // suffix of a closure, such as ".func1" or "-fm"
+	TypeArgs        string // type arguments, such as "[...]"
+	Args            []Word // words of Params
+	ArgsElided      bool   // whether words of Params were elided as "..."
 }
 
 type Frame struct {
-	Reason string
-	Size   int
+	Reason         string
+	Size           int
+	LockedToThread bool
+	Elided         int // frames elided by the runtime: 0 if none, -1 if elided without a count
 
 	Head
-	Stacks []Stack
+	Stacks  []Stack
+	Creator *Creator
+	Labels  map[string]string
 
 	LockInfo
 }
 
+// Creator is the go statement that started a goroutine.
+type Creator struct {
+	FuncName string
+	Location string
+	GID      int // goroutine running the go statement, 0 before Go 1.21
+}
+
 type LockInfo struct {
 	*Stack
 	LockType    string
@@ -42,11 +72,94 @@ type Dump struct {
 	RawFrames    map[string]*Frame
 	Surmary      map[string]int64
 	Goroutines   map[int]int
+	Children     map[int][]int
+	Addresses    map[uint64][]Occurrence // goroutines passed an object, by its address
+
+	Source Source
+	Crash  *Crash // crash that made the process print its goroutines, if any
+
+	Truncated int // lines longer than the maximum line size, cut
+
+	Instances []*Dump // dumps merged into this one, by Merge, one per instance
+
+	Findings []Finding // findings of the report the dump was saved by, most severe first
+
+	options Options
+	locks   []LockPattern // lock patterns of options, then the built-in ones
+}
+
+// Finding is a problem found in a dump by a report, saved with the dump
+// so that the findings of an encoded dump can be read without analysing
+// it again.
+type Finding struct {
+	Kind     string
+	Severity int    // higher for more severe findings
+	Summary  string // one line description
+	GIDs     []int  // goroutines involved, ordered
+	Minutes  int    // longest wait of the goroutines involved
+	Instance int    // instance of a merged dump it was found on, from 1, 0 if not merged
+}
+
+// Options are the options a dump is parsed and analysed with.
+type Options struct {
+	LockPatterns []LockPattern // patterns of locks, tried before the built-in ones
+}
+
+// SetOptions sets the options p is analysed with, and those it is
+// parsed with if it is not parsed yet. The lock each goroutine waits for
+// is recorded once parsed, so it is recorded again if the lock patterns
+// change.
+func (p *Dump) SetOptions(o Options) {
+	changed := !samePatterns(p.options.LockPatterns, o.LockPatterns)
+	p.options = o
+	p.locks = nil
+	if len(o.LockPatterns) > 0 {
+		p.locks = append(append([]LockPattern(nil), o.LockPatterns...), builtinLockPatterns...)
+	}
+	if changed {
+		p.checkHoldLocks()
+	}
+	for _, instance := range p.Instances {
+		instance.SetOptions(o)
+	}
+}
+
+// lockPatterns returns the lock patterns p is analysed with.
+func (p *Dump) lockPatterns() []LockPattern {
+	if p.locks == nil {
+		return builtinLockPatterns
+	}
+	return p.locks
+}
+
+// checkHoldLocks records the lock each goroutine of p waits for.
+func (p *Dump) checkHoldLocks() {
+	patterns := p.lockPatterns()
+	for _, f := range p.RawFrames {
+		f.checkHoldLock(patterns)
+	}
+	for key, tf := range p.TrimedFrames {
+		tf.checkHoldLock(patterns)
+		p.TrimedFrames[key] = tf
+	}
+}
+
+// Source describes where a dump was read from.
+type Source struct {
+	Name string // file the dump was read from
+
+	// A file may hold several snapshots of a process, taken over time.
+	Index   int       // position of the snapshot in the file, from 0
+	Process string    // name of the process dumped, if known
+	PID     int       // pid of the process dumped, if known
+	Time    time.Time // time the snapshot was taken, if known
 }
 
 type TrimedFrame struct {
 	Frame
-	Heads []Head
+	Heads     []Head
+	Count     int   // number of goroutines, which may have no Heads
+	Instances []int // goroutines on each instance of a merged dump
 }
 
 func NewDump() (p *Dump) {
@@ -55,6 +168,8 @@ func NewDump() (p *Dump) {
 		TrimedFrames: make(map[string]TrimedFrame),
 		Surmary:      make(map[string]int64),
 		Goroutines:   make(map[int]int),
+		Children:     make(map[int][]int),
+		Addresses:    make(map[uint64][]Occurrence),
 	}
 	return
 }
@@ -77,13 +192,51 @@ func (p *Dump) InsertRawFrame(f *Frame) {
 	key := fmt.Sprintf("%d_%d", f.GID, f.Duration)
 	p.RawFrames[key] = f
 	p.Surmary[f.Reason]++
+	// GIDs are unique in a snapshot, as a repeated GID starts a new one.
 	p.Goroutines[f.GID] = f.Duration
+	if f.Creator != nil && f.Creator.GID > 0 {
+		p.Children[f.Creator.GID] = append(p.Children[f.Creator.GID], f.GID)
+	}
+	p.indexAddresses(f)
 }
 
 func (p *Dump) GetFrameByGID(gid int) (frame *Frame) {
 	return p.getFrameByGID(gid, p.Goroutines[gid])
 }
 
+// GetCreatorByGID returns the go statement that started goroutine gid,
+// or nil if it is unknown.
+func (p *Dump) GetCreatorByGID(gid int) *Creator {
+	f := p.GetFrameByGID(gid)
+	if f == nil {
+		return nil
+	}
+	return f.Creator
+}
+
+// GetChildrenByGID returns the goroutines started by goroutine gid.
+// Only dumps of Go 1.21 and later record the parent of a goroutine.
+func (p *Dump) GetChildrenByGID(gid int) (frames []*Frame) {
+	for _, child := range p.Children[gid] {
+		if f := p.GetFrameByGID(child); f != nil {
+			frames = append(frames, f)
+		}
+	}
+	return
+}
+
+// GetFramesByCreator returns the goroutines started by function
+// funcName, ordered by goroutine ID.
+func (p *Dump) GetFramesByCreator(funcName string) (frames []*Frame) {
+	for _, f := range p.RawFrames {
+		if f.Creator != nil && f.Creator.FuncName == funcName {
+			frames = append(frames, f)
+		}
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i].GID < frames[j].GID })
+	return
+}
+
 func (p *Dump) getFrameByGID(gid, idx int) (frame *Frame) {
 	key := fmt.Sprintf("%d_%d", gid, idx)
 	f, ok := p.RawFrames[key]
@@ -122,92 +275,225 @@ func (p *Dump) InsertTrimedFrame(f *Frame) {
 		}
 		tf.Heads = append(tf.Heads, f.Head)
 	}
+	tf.Count++
 	p.TrimedFrames[key] = tf
 }
 
-func (p *Dump) unmarshal(data string) {
-	var elems []string
-	if elems = strings.Split(data, "\n\n"); len(elems) <= 1 {
-		// try another one
-		if elems = strings.Split(data, "\n\r\n"); len(elems) <= 1 {
-			return
+// InsertCountedFrame inserts count goroutines sharing the stack of f,
+// as printed by aggregated goroutine profiles which omit goroutine IDs.
+func (p *Dump) InsertCountedFrame(f *Frame, count int) {
+	key := p.genTrimedKey(f, 0)
+	tf, ok := p.TrimedFrames[key]
+	if !ok {
+		tf = TrimedFrame{
+			Frame: *f,
 		}
 	}
+	tf.Count += count
+	p.TrimedFrames[key] = tf
+	p.Surmary[f.Reason] += int64(count)
+}
 
-	for _, elem := range elems {
-		frame := &Frame{}
-		var lines []string
-		if lines = strings.Split(elem, "\n"); len(lines) == 1 {
-			return
+// decode reads the goroutines of a text dump from r and inserts them
+// into the dump as they are decoded.
+func (p *Dump) decode(r io.Reader) error {
+	name, options := p.Source.Name, p.options
+	return decodeText(r, func(src Source) *Dump {
+		// Only the latest snapshot is kept.
+		*p = *NewDump()
+		p.SetOptions(options)
+		p.Source = src
+		p.Source.Name = name
+		return p
+	})
+}
+
+// decodeText decodes the snapshots of a text dump, each into the dump
+// returned by snapshot when the snapshot starts.
+func decodeText(r io.Reader, snapshot func(Source) *Dump) error {
+	d := newTextDecoder(r)
+	var p *Dump
+	for {
+		frame, count, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// Goroutines without an ID, such as the idle goroutine 0 of
+		// some crashes, are left out, and so are snapshots of only
+		// them.
+		if count == 0 && frame.GID <= 0 {
+			continue
 		}
-		for i := 0; i < len(lines); i++ {
-			if strings.HasPrefix(lines[i], "goroutine") {
-				frame.decodeHead(lines[i])
-				frame.decodeBody(lines[i+1:])
-				break
-			}
+		if p == nil || p.Source.Index != d.source.Index {
+			p = snapshot(d.source)
+			p.Crash = d.crash
 		}
-		if frame.GID > 0 {
+		p.Truncated += d.truncated
+		d.truncated = 0
+		frame.checkHoldLock(p.lockPatterns())
+		if count > 0 {
+			p.InsertCountedFrame(frame, count)
+		} else {
 			p.InsertTrimedFrame(frame)
 			p.InsertRawFrame(frame)
-
-			// TODO 这里可能会存在相同的GID
-			p.Goroutines[frame.GID] = frame.Duration
 		}
 	}
-	return
+	if d.read == 0 {
+		return errNoData
+	}
+	if p == nil {
+		return errors.New("cannot unmarshal file")
+	}
+	p.Truncated += d.truncated
+	return nil
 }
 
-// Parse parses a dump and checks for its validity. The input
-// may be a gzip-compressed encoded protobuf or one of many legacy
-// dump formats which may be unsupported in the future.
+// Parse parses a dump, with the options set by SetOptions, and checks
+// for its validity. The input may be an encoded protobuf or one of many
+// legacy dump formats which may be unsupported in the future, compressed
+// with gzip, bzip2 or zstd.
 func (p *Dump) Parse(r io.Reader) error {
-	data, err := ioutil.ReadAll(r)
+	br, err := decompress(r)
 	if err != nil {
 		return err
 	}
-	return p.ParseData(string(data))
+	defer br.Close()
+	header, _ := br.Peek(headerSize)
+	if isEncoded(header) || isProfile(header) {
+		data, err := ioutil.ReadAll(br)
+		if err != nil {
+			return err
+		}
+		return p.parseBinary(data)
+	}
+	if err := p.decode(br.Reader); err != nil {
+		return fmt.Errorf("parsing dump: %v", err)
+	}
+	return nil
 }
 
-// ParseData parses a dump from a buffer and checks for its
-// validity.
-func (p *Dump) ParseData(data string) error {
-	var err error
-	if err = p.ParseUncompressed(data); err != nil {
+// headerSize is the number of bytes looked at to identify the format
+// of a dump.
+const headerSize = 64
+
+// parseBinary parses a dump that starts like an encoded one, falling
+// back to parsing it as text if it fails to decode.
+func (p *Dump) parseBinary(data []byte) error {
+	err := p.parseEncoded(data)
+	if err == nil || isEncoded(data) {
 		return err
 	}
+	if p.decode(bytes.NewReader(data)) != nil {
+		return err
+	}
+	return nil
+}
 
-	if err != nil {
+// parseEncoded parses a dump encoded as dump.proto, or a goroutine
+// profile encoded as profile.proto.
+func (p *Dump) parseEncoded(data []byte) error {
+	if !isEncoded(data) {
+		x := new(pbProfile)
+		if err := unmarshal(data, x); err != nil {
+			return fmt.Errorf("parsing profile: %v", err)
+		}
+		return decodeProfile(x, p)
+	}
+	x := new(pbDump)
+	if err := unmarshal(data, x); err != nil {
 		return fmt.Errorf("parsing dump: %v", err)
 	}
-
+	if err := decodeDump(x, p); err != nil {
+		return err
+	}
+	// The locks recorded were recognised by the built-in patterns.
+	if len(p.options.LockPatterns) > 0 {
+		p.checkHoldLocks()
+	}
+	for _, instance := range p.Instances {
+		instance.SetOptions(p.options)
+	}
 	return nil
 }
 
+// ParseSnapshots parses the snapshots of a dump taken over time, as
+// appended to the same log, in the order they were taken, with options
+// o. Unlike Parse, which only keeps the latest snapshot, it returns
+// every snapshot.
+func ParseSnapshots(r io.Reader, o Options) ([]*Dump, error) {
+	br, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close()
+	var text io.Reader = br.Reader
+	header, _ := br.Peek(headerSize)
+	if isEncoded(header) || isProfile(header) {
+		data, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		p := NewDump()
+		p.SetOptions(o)
+		err = p.parseEncoded(data)
+		if err == nil || isEncoded(data) {
+			return []*Dump{p}, err
+		}
+		// Not a profile after all, but text starting like one.
+		text = bytes.NewReader(data)
+	}
+	var ps []*Dump
+	err = decodeText(text, func(src Source) *Dump {
+		p := NewDump()
+		p.SetOptions(o)
+		p.Source = src
+		ps = append(ps, p)
+		return p
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parsing dump: %v", err)
+	}
+	return ps, nil
+}
+
+// ParseData parses a dump from a buffer and checks for its
+// validity.
+func (p *Dump) ParseData(data string) error {
+	return p.Parse(strings.NewReader(data))
+}
+
 var errNoData = fmt.Errorf("empty input file")
 
-// ParseUncompressed parses an uncompressed protobuf into a dump.
+// ParseUncompressed parses an uncompressed protobuf or text dump.
 func (p *Dump) ParseUncompressed(data string) (err error) {
 	if len(data) == 0 {
 		return errNoData
 	}
-	p.unmarshal(data)
-	if len(p.RawFrames) <= 1 {
-		return errors.New("cannot unmarshal file")
+	header := data
+	if len(header) > headerSize {
+		header = header[:headerSize]
 	}
-	return nil
+	if isEncoded([]byte(header)) || isProfile([]byte(header)) {
+		return p.parseBinary([]byte(data))
+	}
+	return p.decode(strings.NewReader(data))
 }
 
 func serialize(p *Dump) []byte {
-	return nil
+	return marshal(encodeDump(p))
 }
 
 // Write writes the dump as a gzip-compressed marshaled protobuf.
 func (p *Dump) Write(w io.Writer) error {
 	zw := gzip.NewWriter(w)
-	defer zw.Close()
-	_, err := zw.Write(serialize(p))
-	return err
+	if _, err := zw.Write(serialize(p)); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
 }
 
 // WriteUncompressed writes the dump as a marshaled protobuf.
@@ -0,0 +1,149 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// goroutine is what is checked of a goroutine of a parsed dump.
+type goroutine struct {
+	gid      int
+	reason   string
+	duration int
+	locked   bool
+	elided   int
+	creator  int
+	stacks   int
+}
+
+// parseFile parses the dump in testdata/name.
+func parseFile(t *testing.T, name string) *Dump {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p := NewDump()
+	p.Source.Name = name
+	if err := p.Parse(f); err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return p
+}
+
+func TestParseCorpus(t *testing.T) {
+	for _, tc := range []struct {
+		file       string
+		goroutines []goroutine
+	}{
+		{"go1.16.txt", []goroutine{
+			{1, "chan receive", 12, false, 0, 0, 1},
+			{18, "syscall", 12, false, 0, 0, 2},
+			{34, "semacquire", 3, false, 0, 0, 4},
+			{35, "select", 0, false, -1, 0, 1},
+		}},
+		{"go1.17.txt", []goroutine{
+			{1, "chan receive", 7, false, 0, 0, 1},
+			{21, "semacquire", 0, false, 0, 0, 6},
+			{22, "IO wait", 7, false, 0, 0, 7},
+		}},
+		{"go1.18.txt", []goroutine{
+			{1, "select (no cases)", 9, false, 0, 0, 1},
+			{6, "chan send", 9, false, 0, 0, 2},
+			{7, "runnable", 0, true, 0, 0, 2},
+		}},
+		{"go1.20.txt", []goroutine{
+			{1, "chan receive", 3, false, 0, 0, 1},
+			{40, "sync.Mutex.Lock", 2, false, 0, 0, 4},
+			{41, "sync.RWMutex.RLock", 0, false, 0, 0, 3},
+			{42, "sync.Cond.Wait", 2, false, 0, 0, 3},
+		}},
+		{"go1.21.txt", []goroutine{
+			{1, "chan receive", 14, false, 0, 0, 1},
+			{8, "select", 14, true, 0, 1, 1},
+			{93, "semacquire", 6, false, 0, 8, 3},
+			{120, "running", 0, false, 154, 93, 4},
+		}},
+		{"go1.23.txt", []goroutine{
+			{1, "chan receive", 4, false, 0, 0, 6},
+			{17, "chan send", 4, false, 0, 1, 6},
+		}},
+		{"panic.txt", []goroutine{
+			{1, "running", 0, false, 0, 0, 1},
+		}},
+		{"concurrent-map-writes.txt", []goroutine{
+			{10, "running", 0, false, 0, 1, 2},
+		}},
+		{"sigsegv.txt", []goroutine{
+			{1, "chan receive", 0, false, 0, 0, 1},
+			{7, "syscall", 0, false, 0, 1, 3},
+		}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			p := parseFile(t, tc.file)
+			var gids []int
+			for gid := range p.Goroutines {
+				gids = append(gids, gid)
+			}
+			sort.Ints(gids)
+			if len(gids) != len(tc.goroutines) {
+				t.Fatalf("got goroutines %v, want %d", gids, len(tc.goroutines))
+			}
+			for i, want := range tc.goroutines {
+				if gids[i] != want.gid {
+					t.Errorf("goroutine %d: got GID %d, want %d", i, gids[i], want.gid)
+					continue
+				}
+				f := p.GetFrameByGID(want.gid)
+				creator := 0
+				if f.Creator != nil {
+					creator = f.Creator.GID
+				}
+				got := goroutine{f.GID, f.Reason, f.Duration, f.LockedToThread, f.Elided, creator, len(f.Stacks)}
+				if got != want {
+					t.Errorf("goroutine %d: got %+v, want %+v", want.gid, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSnapshotsSkipsEmpty(t *testing.T) {
+	// The first snapshot holds only the idle goroutine 0 of a crash.
+	const text = `dockerd pid: 2431, time: 2021-12-01T084212Z
+goroutine 0 [idle]:
+runtime.futex(0x5a4c3e8, 0x80, 0x0, 0x0, 0x0, 0x7ffd00000000, 0x0, 0x0, 0x7ffd4fc2b3b8, 0x40e1ef, ...)
+	/usr/local/go/src/runtime/sys_linux_amd64.s:567 +0x21
+
+dockerd pid: 2431, time: 2021-12-01T084712Z
+goroutine 1 [chan receive, 5 minutes]:
+main.main()
+	/src/app/main.go:41 +0x1d2
+
+dockerd pid: 2431, time: 2021-12-01T085212Z
+goroutine 1 [chan receive, 10 minutes]:
+main.main()
+	/src/app/main.go:41 +0x1d2
+`
+	ps, err := ParseSnapshots(strings.NewReader(text), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(ps))
+	}
+	for i, p := range ps {
+		if f := p.GetFrameByGID(1); f == nil || f.Duration != 5*(i+1) {
+			t.Errorf("snapshot %d: got goroutine 1 %+v, want it waiting %d minutes", i, f, 5*(i+1))
+		}
+	}
+
+	p := NewDump()
+	if err := p.ParseData(strings.SplitAfter(text, "+0x21\n")[0]); err == nil {
+		t.Errorf("parsed a dump of no goroutine into %d groups", len(p.TrimedFrames))
+	}
+}
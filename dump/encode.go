@@ -0,0 +1,801 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The messages of dump.proto. Strings are stored once in the string
+// table of the dump and referenced by their index, so the messages are
+// kept apart from the types of the dump and converted on encoding and
+// decoding.
+
+// dumpMagic is the value of the magic field of dump.proto.
+const dumpMagic = "grains.dump"
+
+// dumpHeader is how every encoded dump starts, as the magic field is
+// encoded first.
+var dumpHeader = []byte("\x7a\x0b" + dumpMagic)
+
+type pbDump struct {
+	frames    []*pbFrame
+	groups    []*pbGroup
+	strings   []string
+	source    *pbSource
+	crash     *pbCrash
+	instances []*pbDump
+	findings  []*pbFinding
+	magic     string
+}
+
+type pbFrame struct {
+	gid            int64
+	duration       int64
+	reason         int64
+	size           int64
+	lockedToThread bool
+	elided         int64
+	stacks         []*pbStack
+	creator        *pbCreator
+	labels         []*pbLabel
+	lockInfo       *pbLockInfo
+}
+
+type pbStack struct {
+	funcName int64
+	location int64
+	params   int64
+	pcOffset uint64
+}
+
+type pbCreator struct {
+	funcName int64
+	location int64
+	gid      int64
+}
+
+type pbLabel struct {
+	key   int64
+	value int64
+}
+
+type pbLockInfo struct {
+	stack       int64 // 1-based index of the stack in the frame
+	lockType    int64
+	lockHolders []int64
+}
+
+type pbGroup struct {
+	key            int64
+	frame          *pbFrame
+	heads          []*pbHead
+	count          int64
+	instanceCounts []int64
+}
+
+type pbHead struct {
+	gid      int64
+	duration int64
+	instance int64
+}
+
+type pbCrash struct {
+	kind    int64
+	message int64
+	signal  int64
+	code    int64
+	pc      uint64
+	addr    uint64
+	hasAddr bool
+	gid     int64
+}
+
+type pbFinding struct {
+	kind     int64
+	severity int64
+	summary  int64
+	gids     []int64
+	minutes  int64
+	instance int64
+}
+
+type pbSource struct {
+	name      int64
+	index     int64
+	process   int64
+	pid       int64
+	timeNanos int64
+}
+
+func (p *pbDump) decoder() []decoder {
+	return dumpDecoder
+}
+
+func (p *pbDump) encode(b *buffer) {
+	encodeString(b, 15, p.magic)
+	encodeStrings(b, 3, p.strings)
+	for _, x := range p.frames {
+		encodeMessage(b, 1, x)
+	}
+	for _, x := range p.groups {
+		encodeMessage(b, 2, x)
+	}
+	if p.source != nil {
+		encodeMessage(b, 4, p.source)
+	}
+	if p.crash != nil {
+		encodeMessage(b, 5, p.crash)
+	}
+	for _, x := range p.instances {
+		encodeMessage(b, 6, x)
+	}
+	for _, x := range p.findings {
+		encodeMessage(b, 7, x)
+	}
+}
+
+var dumpDecoder = []decoder{
+	nil, // 0
+	// repeated Frame frame = 1
+	func(b *buffer, m message) error {
+		x := new(pbFrame)
+		p := m.(*pbDump)
+		p.frames = append(p.frames, x)
+		return decodeMessage(b, x)
+	},
+	// repeated Group group = 2
+	func(b *buffer, m message) error {
+		x := new(pbGroup)
+		p := m.(*pbDump)
+		p.groups = append(p.groups, x)
+		return decodeMessage(b, x)
+	},
+	// repeated string string_table = 3
+	func(b *buffer, m message) error { return decodeStrings(b, &m.(*pbDump).strings) },
+	// Source source = 4
+	func(b *buffer, m message) error {
+		x := new(pbSource)
+		m.(*pbDump).source = x
+		return decodeMessage(b, x)
+	},
+	// Crash crash = 5
+	func(b *buffer, m message) error {
+		x := new(pbCrash)
+		m.(*pbDump).crash = x
+		return decodeMessage(b, x)
+	},
+	// repeated Dump instance = 6
+	func(b *buffer, m message) error {
+		x := new(pbDump)
+		p := m.(*pbDump)
+		p.instances = append(p.instances, x)
+		return decodeMessage(b, x)
+	},
+	// repeated Finding finding = 7
+	func(b *buffer, m message) error {
+		x := new(pbFinding)
+		p := m.(*pbDump)
+		p.findings = append(p.findings, x)
+		return decodeMessage(b, x)
+	},
+	nil, nil, nil, nil, nil, nil, nil, // 8-14
+	// string magic = 15
+	func(b *buffer, m message) error { return decodeString(b, &m.(*pbDump).magic) },
+}
+
+func (p *pbFrame) decoder() []decoder {
+	return frameDecoder
+}
+
+func (p *pbFrame) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.gid)
+	encodeInt64Opt(b, 2, p.duration)
+	encodeInt64Opt(b, 3, p.reason)
+	encodeInt64Opt(b, 4, p.size)
+	encodeBoolOpt(b, 5, p.lockedToThread)
+	encodeInt64Opt(b, 6, p.elided)
+	for _, x := range p.stacks {
+		encodeMessage(b, 7, x)
+	}
+	if p.creator != nil {
+		encodeMessage(b, 8, p.creator)
+	}
+	for _, x := range p.labels {
+		encodeMessage(b, 9, x)
+	}
+	if p.lockInfo != nil {
+		encodeMessage(b, 10, p.lockInfo)
+	}
+}
+
+var frameDecoder = []decoder{
+	nil, // 0
+	// int64 gid = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFrame).gid) },
+	// int64 duration = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFrame).duration) },
+	// int64 reason = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFrame).reason) },
+	// int64 size = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFrame).size) },
+	// bool locked_to_thread = 5
+	func(b *buffer, m message) error { return decodeBool(b, &m.(*pbFrame).lockedToThread) },
+	// int64 elided = 6
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFrame).elided) },
+	// repeated Stack stack = 7
+	func(b *buffer, m message) error {
+		x := new(pbStack)
+		p := m.(*pbFrame)
+		p.stacks = append(p.stacks, x)
+		return decodeMessage(b, x)
+	},
+	// Creator creator = 8
+	func(b *buffer, m message) error {
+		x := new(pbCreator)
+		m.(*pbFrame).creator = x
+		return decodeMessage(b, x)
+	},
+	// repeated Label label = 9
+	func(b *buffer, m message) error {
+		x := new(pbLabel)
+		p := m.(*pbFrame)
+		p.labels = append(p.labels, x)
+		return decodeMessage(b, x)
+	},
+	// LockInfo lock_info = 10
+	func(b *buffer, m message) error {
+		x := new(pbLockInfo)
+		m.(*pbFrame).lockInfo = x
+		return decodeMessage(b, x)
+	},
+}
+
+func (p *pbStack) decoder() []decoder {
+	return stackDecoder
+}
+
+func (p *pbStack) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.funcName)
+	encodeInt64Opt(b, 2, p.location)
+	encodeInt64Opt(b, 3, p.params)
+	encodeUint64Opt(b, 4, p.pcOffset)
+}
+
+var stackDecoder = []decoder{
+	nil, // 0
+	// int64 func_name = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbStack).funcName) },
+	// int64 location = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbStack).location) },
+	// int64 params = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbStack).params) },
+	// uint64 pc_offset = 4
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbStack).pcOffset) },
+}
+
+func (p *pbCreator) decoder() []decoder {
+	return creatorDecoder
+}
+
+func (p *pbCreator) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.funcName)
+	encodeInt64Opt(b, 2, p.location)
+	encodeInt64Opt(b, 3, p.gid)
+}
+
+var creatorDecoder = []decoder{
+	nil, // 0
+	// int64 func_name = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCreator).funcName) },
+	// int64 location = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCreator).location) },
+	// int64 gid = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCreator).gid) },
+}
+
+func (p *pbLabel) decoder() []decoder {
+	return labelDecoder
+}
+
+func (p *pbLabel) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.key)
+	encodeInt64Opt(b, 2, p.value)
+}
+
+var labelDecoder = []decoder{
+	nil, // 0
+	// int64 key = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbLabel).key) },
+	// int64 value = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbLabel).value) },
+}
+
+func (p *pbLockInfo) decoder() []decoder {
+	return lockInfoDecoder
+}
+
+func (p *pbLockInfo) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.stack)
+	encodeInt64Opt(b, 2, p.lockType)
+	encodeInt64s(b, 3, p.lockHolders)
+}
+
+var lockInfoDecoder = []decoder{
+	nil, // 0
+	// int64 stack = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbLockInfo).stack) },
+	// int64 lock_type = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbLockInfo).lockType) },
+	// repeated int64 lock_holders = 3
+	func(b *buffer, m message) error { return decodeInt64s(b, &m.(*pbLockInfo).lockHolders) },
+}
+
+func (p *pbGroup) decoder() []decoder {
+	return groupDecoder
+}
+
+func (p *pbGroup) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.key)
+	if p.frame != nil {
+		encodeMessage(b, 2, p.frame)
+	}
+	for _, x := range p.heads {
+		encodeMessage(b, 3, x)
+	}
+	encodeInt64Opt(b, 4, p.count)
+	encodeInt64s(b, 5, p.instanceCounts)
+}
+
+var groupDecoder = []decoder{
+	nil, // 0
+	// int64 key = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbGroup).key) },
+	// Frame frame = 2
+	func(b *buffer, m message) error {
+		x := new(pbFrame)
+		m.(*pbGroup).frame = x
+		return decodeMessage(b, x)
+	},
+	// repeated Head head = 3
+	func(b *buffer, m message) error {
+		x := new(pbHead)
+		p := m.(*pbGroup)
+		p.heads = append(p.heads, x)
+		return decodeMessage(b, x)
+	},
+	// int64 count = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbGroup).count) },
+	// repeated int64 instance_count = 5
+	func(b *buffer, m message) error { return decodeInt64s(b, &m.(*pbGroup).instanceCounts) },
+}
+
+func (p *pbHead) decoder() []decoder {
+	return headDecoder
+}
+
+func (p *pbHead) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.gid)
+	encodeInt64Opt(b, 2, p.duration)
+	encodeInt64Opt(b, 3, p.instance)
+}
+
+var headDecoder = []decoder{
+	nil, // 0
+	// int64 gid = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbHead).gid) },
+	// int64 duration = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbHead).duration) },
+	// int64 instance = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbHead).instance) },
+}
+
+func (p *pbSource) decoder() []decoder {
+	return sourceDecoder
+}
+
+func (p *pbSource) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.name)
+	encodeInt64Opt(b, 2, p.index)
+	encodeInt64Opt(b, 3, p.process)
+	encodeInt64Opt(b, 4, p.pid)
+	encodeInt64Opt(b, 5, p.timeNanos)
+}
+
+var sourceDecoder = []decoder{
+	nil, // 0
+	// int64 name = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSource).name) },
+	// int64 index = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSource).index) },
+	// int64 process = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSource).process) },
+	// int64 pid = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSource).pid) },
+	// int64 time_nanos = 5
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSource).timeNanos) },
+}
+
+func (p *pbCrash) decoder() []decoder {
+	return crashDecoder
+}
+
+func (p *pbCrash) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.kind)
+	encodeInt64Opt(b, 2, p.message)
+	encodeInt64Opt(b, 3, p.signal)
+	encodeInt64Opt(b, 4, p.code)
+	encodeUint64Opt(b, 5, p.pc)
+	encodeUint64Opt(b, 6, p.addr)
+	encodeBoolOpt(b, 7, p.hasAddr)
+	encodeInt64Opt(b, 8, p.gid)
+}
+
+var crashDecoder = []decoder{
+	nil, // 0
+	// int64 kind = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCrash).kind) },
+	// int64 message = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCrash).message) },
+	// int64 signal = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCrash).signal) },
+	// int64 code = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCrash).code) },
+	// uint64 pc = 5
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbCrash).pc) },
+	// uint64 addr = 6
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbCrash).addr) },
+	// bool has_addr = 7
+	func(b *buffer, m message) error { return decodeBool(b, &m.(*pbCrash).hasAddr) },
+	// int64 gid = 8
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbCrash).gid) },
+}
+
+func (p *pbFinding) decoder() []decoder {
+	return findingDecoder
+}
+
+func (p *pbFinding) encode(b *buffer) {
+	encodeInt64Opt(b, 1, p.kind)
+	encodeInt64Opt(b, 2, p.severity)
+	encodeInt64Opt(b, 3, p.summary)
+	encodeInt64s(b, 4, p.gids)
+	encodeInt64Opt(b, 5, p.minutes)
+	encodeInt64Opt(b, 6, p.instance)
+}
+
+var findingDecoder = []decoder{
+	nil, // 0
+	// int64 kind = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFinding).kind) },
+	// int64 severity = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFinding).severity) },
+	// int64 summary = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFinding).summary) },
+	// repeated int64 gid = 4
+	func(b *buffer, m message) error { return decodeInt64s(b, &m.(*pbFinding).gids) },
+	// int64 minutes = 5
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFinding).minutes) },
+	// int64 instance = 6
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFinding).instance) },
+}
+
+// stringTable assigns indices to the strings of an encoded dump.
+type stringTable struct {
+	strings []string
+	index   map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{
+		strings: []string{""},
+		index:   map[string]int64{"": 0},
+	}
+}
+
+func (t *stringTable) add(s string) int64 {
+	if i, ok := t.index[s]; ok {
+		return i
+	}
+	i := int64(len(t.strings))
+	t.strings = append(t.strings, s)
+	t.index[s] = i
+	return i
+}
+
+// get returns the string at index i of the table of an encoded dump.
+func (p *pbDump) get(i int64) (string, error) {
+	if i < 0 || i >= int64(len(p.strings)) {
+		return "", fmt.Errorf("malformed dump: string index %d out of range", i)
+	}
+	return p.strings[i], nil
+}
+
+// encodeDump converts p to its dump.proto message.
+func encodeDump(p *Dump) *pbDump {
+	t := newStringTable()
+	x := &pbDump{magic: dumpMagic}
+
+	var keys []string
+	for k := range p.RawFrames {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		x.frames = append(x.frames, encodeFrame(p.RawFrames[k], t))
+	}
+
+	keys = keys[:0]
+	for k := range p.TrimedFrames {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tf := p.TrimedFrames[k]
+		g := &pbGroup{
+			key:   t.add(k),
+			frame: encodeFrame(&tf.Frame, t),
+			count: int64(tf.Count),
+		}
+		for _, h := range tf.Heads {
+			g.heads = append(g.heads, &pbHead{gid: int64(h.GID), duration: int64(h.Duration), instance: int64(h.Instance)})
+		}
+		for _, n := range tf.Instances {
+			g.instanceCounts = append(g.instanceCounts, int64(n))
+		}
+		x.groups = append(x.groups, g)
+	}
+	for _, instance := range p.Instances {
+		x.instances = append(x.instances, encodeDump(instance))
+	}
+	for _, f := range p.Findings {
+		xf := &pbFinding{
+			kind:     t.add(f.Kind),
+			severity: int64(f.Severity),
+			summary:  t.add(f.Summary),
+			minutes:  int64(f.Minutes),
+			instance: int64(f.Instance),
+		}
+		for _, gid := range f.GIDs {
+			xf.gids = append(xf.gids, int64(gid))
+		}
+		x.findings = append(x.findings, xf)
+	}
+
+	x.source = &pbSource{
+		name:    t.add(p.Source.Name),
+		index:   int64(p.Source.Index),
+		process: t.add(p.Source.Process),
+		pid:     int64(p.Source.PID),
+	}
+	if !p.Source.Time.IsZero() {
+		x.source.timeNanos = p.Source.Time.UnixNano()
+	}
+	if c := p.Crash; c != nil {
+		x.crash = &pbCrash{
+			kind:    t.add(c.Kind),
+			message: t.add(c.Message),
+			signal:  t.add(c.Signal),
+			code:    t.add(c.Code),
+			pc:      c.PC,
+			addr:    c.Addr,
+			hasAddr: c.HasAddr,
+			gid:     int64(c.GID),
+		}
+	}
+	x.strings = t.strings
+	return x
+}
+
+func encodeFrame(f *Frame, t *stringTable) *pbFrame {
+	x := &pbFrame{
+		gid:            int64(f.GID),
+		duration:       int64(f.Duration),
+		reason:         t.add(f.Reason),
+		size:           int64(f.Size),
+		lockedToThread: f.LockedToThread,
+		elided:         int64(f.Elided),
+	}
+	for i := range f.Stacks {
+		s := &f.Stacks[i]
+		x.stacks = append(x.stacks, &pbStack{
+			funcName: t.add(s.FuncName),
+			location: t.add(s.Location),
+			params:   t.add(s.Params),
+			pcOffset: s.PCOffset,
+		})
+		if f.LockInfo.Stack == s {
+			x.lockInfo = &pbLockInfo{stack: int64(i + 1)}
+		}
+	}
+	if c := f.Creator; c != nil {
+		x.creator = &pbCreator{
+			funcName: t.add(c.FuncName),
+			location: t.add(c.Location),
+			gid:      int64(c.GID),
+		}
+	}
+	var keys []string
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		x.labels = append(x.labels, &pbLabel{key: t.add(k), value: t.add(f.Labels[k])})
+	}
+	if f.LockType != "" || len(f.LockHolders) > 0 {
+		if x.lockInfo == nil {
+			x.lockInfo = &pbLockInfo{}
+		}
+		x.lockInfo.lockType = t.add(f.LockType)
+		for _, h := range f.LockHolders {
+			x.lockInfo.lockHolders = append(x.lockInfo.lockHolders, t.add(h))
+		}
+	}
+	return x
+}
+
+// decodeDump loads the dump.proto message x into p.
+func decodeDump(x *pbDump, p *Dump) error {
+	if x.magic != dumpMagic {
+		return fmt.Errorf("malformed dump: bad magic %q", x.magic)
+	}
+	for _, xf := range x.frames {
+		f, err := x.decodeFrame(xf)
+		if err != nil {
+			return err
+		}
+		p.RawFrames[fmt.Sprintf("%d_%d", f.GID, f.Duration)] = f
+		p.Goroutines[f.GID] = f.Duration
+		if f.Creator != nil && f.Creator.GID > 0 {
+			p.Children[f.Creator.GID] = append(p.Children[f.Creator.GID], f.GID)
+		}
+		p.indexAddresses(f)
+	}
+	for _, g := range x.groups {
+		key, err := x.get(g.key)
+		if err != nil {
+			return err
+		}
+		if g.frame == nil {
+			return fmt.Errorf("malformed dump: group %s has no frame", key)
+		}
+		f, err := x.decodeFrame(g.frame)
+		if err != nil {
+			return err
+		}
+		tf := TrimedFrame{Frame: *f, Count: int(g.count)}
+		for _, h := range g.heads {
+			tf.Heads = append(tf.Heads, Head{GID: int(h.gid), Duration: int(h.duration), Instance: int(h.instance)})
+		}
+		for _, n := range g.instanceCounts {
+			tf.Instances = append(tf.Instances, int(n))
+		}
+		p.TrimedFrames[key] = tf
+		p.Surmary[f.Reason] += int64(tf.Count)
+	}
+	if x.source != nil {
+		name, err := x.get(x.source.name)
+		if err != nil {
+			return err
+		}
+		process, err := x.get(x.source.process)
+		if err != nil {
+			return err
+		}
+		p.Source = Source{
+			Name:    name,
+			Index:   int(x.source.index),
+			Process: process,
+			PID:     int(x.source.pid),
+		}
+		if x.source.timeNanos != 0 {
+			p.Source.Time = time.Unix(0, x.source.timeNanos).UTC()
+		}
+	}
+	for _, xf := range x.findings {
+		kind, err := x.get(xf.kind)
+		if err != nil {
+			return err
+		}
+		summary, err := x.get(xf.summary)
+		if err != nil {
+			return err
+		}
+		f := Finding{Kind: kind, Severity: int(xf.severity), Summary: summary, Minutes: int(xf.minutes), Instance: int(xf.instance)}
+		for _, gid := range xf.gids {
+			f.GIDs = append(f.GIDs, int(gid))
+		}
+		p.Findings = append(p.Findings, f)
+	}
+	for _, xi := range x.instances {
+		instance := NewDump()
+		if err := decodeDump(xi, instance); err != nil {
+			return err
+		}
+		p.Instances = append(p.Instances, instance)
+	}
+	if xc := x.crash; xc != nil {
+		return x.decodeCrash(xc, p)
+	}
+	return nil
+}
+
+func (x *pbDump) decodeCrash(xc *pbCrash, p *Dump) error {
+	var err error
+	get := func(i int64) string {
+		s, e := x.get(i)
+		if err == nil {
+			err = e
+		}
+		return s
+	}
+	p.Crash = &Crash{
+		Kind:    get(xc.kind),
+		Message: get(xc.message),
+		Signal:  get(xc.signal),
+		Code:    get(xc.code),
+		PC:      xc.pc,
+		Addr:    xc.addr,
+		HasAddr: xc.hasAddr,
+		GID:     int(xc.gid),
+	}
+	return err
+}
+
+func (x *pbDump) decodeFrame(xf *pbFrame) (*Frame, error) {
+	var err error
+	get := func(i int64) string {
+		s, e := x.get(i)
+		if err == nil {
+			err = e
+		}
+		return s
+	}
+	f := &Frame{
+		Reason:         get(xf.reason),
+		Size:           int(xf.size),
+		LockedToThread: xf.lockedToThread,
+		Elided:         int(xf.elided),
+		Head:           Head{GID: int(xf.gid), Duration: int(xf.duration)},
+	}
+	for _, s := range xf.stacks {
+		stack := Stack{
+			FuncName: get(s.funcName),
+			Location: get(s.location),
+			Params:   get(s.params),
+			PCOffset: s.pcOffset,
+		}
+		stack.decode()
+		f.Stacks = append(f.Stacks, stack)
+	}
+	if c := xf.creator; c != nil {
+		f.Creator = &Creator{
+			FuncName: get(c.funcName),
+			Location: get(c.location),
+			GID:      int(c.gid),
+		}
+	}
+	for _, l := range xf.labels {
+		if f.Labels == nil {
+			f.Labels = make(map[string]string)
+		}
+		f.Labels[get(l.key)] = get(l.value)
+	}
+	if l := xf.lockInfo; l != nil {
+		if l.stack > 0 && l.stack <= int64(len(f.Stacks)) {
+			f.LockInfo.Stack = &f.Stacks[l.stack-1]
+		}
+		f.LockType = get(l.lockType)
+		for _, h := range l.lockHolders {
+			f.LockHolders = append(f.LockHolders, get(h))
+		}
+	}
+	return f, err
+}
+
+// isEncoded reports whether data starts like an encoded dump.
+func isEncoded(data []byte) bool {
+	return bytes.HasPrefix(data, dumpHeader)
+}
@@ -0,0 +1,84 @@
+package dump
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteParse(t *testing.T) {
+	for _, file := range []string{"go1.16.txt", "go1.20.txt", "go1.21.txt", "debug1.txt", "panic.txt"} {
+		t.Run(file, func(t *testing.T) {
+			p := parseFile(t, file)
+			p.Source.Process = "app"
+			p.Source.PID = 42
+			p.Source.Time = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+			p.Findings = []Finding{
+				{Kind: "deadlock", Severity: 120, Summary: "goroutines 5 6 wait for each other's locks", GIDs: []int{5, 6}, Minutes: 3},
+				{Kind: "crash", Severity: 150, Summary: "panic: boom"},
+			}
+
+			var buf bytes.Buffer
+			if err := p.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			p2 := NewDump()
+			if err := p2.Parse(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(p2.TrimedFrames, p.TrimedFrames) {
+				t.Errorf("got groups %+v, want %+v", p2.TrimedFrames, p.TrimedFrames)
+			}
+			if !reflect.DeepEqual(p2.RawFrames, p.RawFrames) {
+				t.Errorf("got goroutines %+v, want %+v", p2.RawFrames, p.RawFrames)
+			}
+			if p2.Source != p.Source {
+				t.Errorf("got source %+v, want %+v", p2.Source, p.Source)
+			}
+			if !reflect.DeepEqual(p2.Crash, p.Crash) {
+				t.Errorf("got crash %+v, want %+v", p2.Crash, p.Crash)
+			}
+			if !reflect.DeepEqual(p2.Findings, p.Findings) {
+				t.Errorf("got findings %+v, want %+v", p2.Findings, p.Findings)
+			}
+			// Creators and locks are pointers, checked apart for clearer failures.
+			for key, tf := range p.TrimedFrames {
+				if tf2 := p2.TrimedFrames[key]; !reflect.DeepEqual(tf2.Creator, tf.Creator) || !reflect.DeepEqual(tf2.LockInfo, tf.LockInfo) {
+					t.Errorf("%s: got creator %+v and lock %+v, want %+v and %+v", key, tf2.Creator, tf2.LockInfo, tf.Creator, tf.LockInfo)
+				}
+			}
+		})
+	}
+	t.Run("merged", func(t *testing.T) {
+		p := Merge([]*Dump{parseFile(t, "go1.20.txt"), parseFile(t, "go1.21.txt")})
+		var buf bytes.Buffer
+		if err := p.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		p2 := NewDump()
+		if err := p2.Parse(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if !p2.Merged() || len(p2.Instances) != len(p.Instances) {
+			t.Fatalf("got %d instances, want %d", len(p2.Instances), len(p.Instances))
+		}
+		if !reflect.DeepEqual(p2.TrimedFrames, p.TrimedFrames) {
+			t.Errorf("got groups %+v, want %+v", p2.TrimedFrames, p.TrimedFrames)
+		}
+		if !reflect.DeepEqual(p2.Surmary, p.Surmary) {
+			t.Errorf("got summary %v, want %v", p2.Surmary, p.Surmary)
+		}
+		for i, instance := range p.Instances {
+			instance2 := p2.Instances[i]
+			if !reflect.DeepEqual(instance2.RawFrames, instance.RawFrames) {
+				t.Errorf("instance %d: got goroutines %+v, want %+v", i+1, instance2.RawFrames, instance.RawFrames)
+			}
+			if instance2.Source != instance.Source {
+				t.Errorf("instance %d: got source %+v, want %+v", i+1, instance2.Source, instance.Source)
+			}
+		}
+	})
+}
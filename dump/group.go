@@ -0,0 +1,139 @@
+package dump
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Grouping strategies, from the finest to the coarsest. Goroutines are
+// grouped by reason, creator and labels too, except by GroupTop and
+// GroupPackage, which leave out the creator.
+const (
+	GroupExact       = "exact"        // same functions and locations, as parsed
+	GroupIgnoreLines = "ignore_lines" // same functions and files
+	GroupFunctions   = "functions"    // same functions, recursive calls counted once
+	GroupTop         = "top"          // same innermost functions, past the runtime
+	GroupBottom      = "bottom"       // same outermost functions, up to goexit
+	GroupPackage     = "package"      // same packages, consecutive calls in one counted once
+)
+
+// Grouping is how goroutines are grouped.
+type Grouping struct {
+	Strategy string
+	Frames   int // number of frames compared by GroupTop and GroupBottom
+}
+
+// Check checks that g is a known strategy with the frames it needs.
+func (g Grouping) Check() error {
+	switch g.Strategy {
+	case "", GroupExact, GroupIgnoreLines, GroupFunctions, GroupPackage:
+		return nil
+	case GroupTop, GroupBottom:
+		if g.Frames < 1 {
+			return fmt.Errorf("grouping by %s frames needs at least 1 frame, not %d", g.Strategy, g.Frames)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown grouping %q", g.Strategy)
+}
+
+// key returns the key of the group of f.
+func (g Grouping) key(f *Frame) string {
+	var parts []string
+	add := func(part string) {
+		// Recursive calls, and consecutive calls in a package, are
+		// counted once.
+		if (g.Strategy == GroupFunctions || g.Strategy == GroupPackage) &&
+			len(parts) > 0 && parts[len(parts)-1] == part {
+			return
+		}
+		parts = append(parts, part)
+	}
+	stacks := f.Stacks
+	switch g.Strategy {
+	case GroupTop:
+		for len(stacks) > 0 && stacks[0].ImportPath == "runtime" {
+			stacks = stacks[1:]
+		}
+		if len(stacks) > g.Frames {
+			stacks = stacks[:g.Frames]
+		}
+	case GroupBottom:
+		for len(stacks) > 0 && stacks[len(stacks)-1].ImportPath == "runtime" {
+			stacks = stacks[:len(stacks)-1]
+		}
+		if len(stacks) > g.Frames {
+			stacks = stacks[len(stacks)-g.Frames:]
+		}
+	}
+	for _, s := range stacks {
+		switch g.Strategy {
+		case GroupIgnoreLines:
+			add(s.FuncName + " " + s.File)
+		case GroupPackage:
+			add(s.ImportPath)
+		default:
+			add(s.FuncName)
+		}
+	}
+	if f.Creator != nil && g.Strategy != GroupTop && g.Strategy != GroupPackage {
+		parts = append(parts, "created by "+f.Creator.FuncName)
+	}
+	keys := make([]string, 0, len(f.Labels))
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+f.Labels[k])
+	}
+	return f.Reason + "\n" + strings.Join(parts, "\n")
+}
+
+// Regroup returns the goroutine groups of p grouped by g, keyed by
+// reason and index as the groups parsed. Goroutines are grouped exactly
+// when parsed, so other strategies merge whole groups, the stack of the
+// largest of which stands for the merged group.
+func (p *Dump) Regroup(g Grouping) map[string]TrimedFrame {
+	if g.Strategy == "" || g.Strategy == GroupExact {
+		return p.TrimedFrames
+	}
+	keys := sortedKeys(p.TrimedFrames)
+	// The largest group first, so that it stands for the merged group.
+	sort.SliceStable(keys, func(i, j int) bool {
+		return p.TrimedFrames[keys[i]].Count > p.TrimedFrames[keys[j]].Count
+	})
+	var order []string
+	merged := make(map[string]*TrimedFrame)
+	for _, key := range keys {
+		tf := p.TrimedFrames[key]
+		k := g.key(&tf.Frame)
+		m := merged[k]
+		if m == nil {
+			m = &TrimedFrame{Frame: tf.Frame}
+			if tf.Instances != nil {
+				m.Instances = make([]int, len(tf.Instances))
+			}
+			merged[k] = m
+			order = append(order, k)
+		}
+		m.Heads = append(m.Heads, tf.Heads...)
+		m.Count += tf.Count
+		for i, n := range tf.Instances {
+			m.Instances[i] += n
+		}
+		if minutes := tf.Minutes(); minutes > m.Duration {
+			m.Duration = minutes
+		}
+	}
+
+	groups := make(map[string]TrimedFrame, len(order))
+	index := make(map[string]int)
+	for _, k := range order {
+		m := merged[k]
+		groups[fmt.Sprintf("%s_%d", m.Reason, index[m.Reason])] = *m
+		index[m.Reason]++
+	}
+	return groups
+}
@@ -0,0 +1,181 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// groupDump holds goroutines 1 and 2 walking a tree, 3 walking it
+// recursively, 4 walking it from another line, 5 and 6 reading a store
+// from the same package, 6 created by another function.
+const groupDump = `goroutine 1 [chan receive, 3 minutes]:
+main.walk(0xc000010000)
+	/src/app/main.go:20 +0x45
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+
+goroutine 2 [chan receive, 3 minutes]:
+main.walk(0xc000010040)
+	/src/app/main.go:20 +0x45
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+
+goroutine 3 [chan receive, 7 minutes]:
+main.walk(0xc000010080)
+	/src/app/main.go:20 +0x45
+main.walk(0xc0000100c0)
+	/src/app/main.go:22 +0x65
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+
+goroutine 4 [chan receive]:
+main.walk(0xc000010100)
+	/src/app/main.go:21 +0x51
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+
+goroutine 5 [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:398 +0xce
+example.com/app/store.(*DB).get(0xc000020000)
+	/src/app/store/store.go:40 +0x45
+example.com/app/store.(*DB).Get(0xc000020000)
+	/src/app/store/store.go:30 +0x25
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+
+goroutine 6 [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:398 +0xce
+example.com/app/store.(*DB).Get(0xc000020000)
+	/src/app/store/store.go:30 +0x25
+main.run()
+	/src/app/main.go:30 +0x25
+created by main.serve in goroutine 1
+	/src/app/main.go:9 +0x5a
+`
+
+// groupGIDs returns the goroutines of each of groups, in order.
+func groupGIDs(groups map[string]TrimedFrame) [][]int {
+	var gids [][]int
+	for _, tf := range groups {
+		var g []int
+		for _, h := range tf.Heads {
+			g = append(g, h.GID)
+		}
+		sort.Ints(g)
+		gids = append(gids, g)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i][0] < gids[j][0] })
+	return gids
+}
+
+func TestRegroup(t *testing.T) {
+	p := NewDump()
+	if err := p.ParseData(groupDump); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		grouping Grouping
+		want     [][]int
+	}{
+		{Grouping{}, [][]int{{1, 2}, {3}, {4}, {5}, {6}}},
+		{Grouping{Strategy: GroupExact}, [][]int{{1, 2}, {3}, {4}, {5}, {6}}},
+		{Grouping{Strategy: GroupIgnoreLines}, [][]int{{1, 2, 4}, {3}, {5}, {6}}},
+		{Grouping{Strategy: GroupFunctions}, [][]int{{1, 2, 3, 4}, {5}, {6}}},
+		{Grouping{Strategy: GroupTop, Frames: 1}, [][]int{{1, 2, 3, 4}, {5}, {6}}},
+		{Grouping{Strategy: GroupTop, Frames: 2}, [][]int{{1, 2, 4}, {3}, {5}, {6}}},
+		{Grouping{Strategy: GroupBottom, Frames: 1}, [][]int{{1, 2, 3, 4, 5}, {6}}},
+		{Grouping{Strategy: GroupPackage}, [][]int{{1, 2, 3, 4}, {5, 6}}},
+	} {
+		name := tc.grouping.Strategy
+		if tc.grouping.Frames > 0 {
+			name += fmt.Sprintf(" %d", tc.grouping.Frames)
+		}
+		t.Run(name, func(t *testing.T) {
+			if err := tc.grouping.Check(); err != nil {
+				t.Fatal(err)
+			}
+			groups := p.Regroup(tc.grouping)
+			if got := groupGIDs(groups); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got groups %v, want %v", got, tc.want)
+			}
+			for key, tf := range groups {
+				if !strings.HasPrefix(key, tf.Reason+"_") || tf.Count != len(tf.Heads) {
+					t.Errorf("got group %s of %d goroutines with %d heads", key, tf.Count, len(tf.Heads))
+				}
+			}
+		})
+	}
+
+	// The largest group stands for the merged one, which waits as long
+	// as its oldest goroutine.
+	for _, tf := range p.Regroup(Grouping{Strategy: GroupFunctions}) {
+		if tf.Count != 4 {
+			continue
+		}
+		if len(tf.Stacks) != 2 || tf.Stacks[0].Line != 20 {
+			t.Errorf("got merged group of stack %+v, want that of goroutines 1 and 2", tf.Stacks)
+		}
+		if tf.Minutes() != 7 {
+			t.Errorf("got merged group waiting %d minutes, want 7", tf.Minutes())
+		}
+	}
+}
+
+func TestRegroupMerged(t *testing.T) {
+	var ps []*Dump
+	for _, gids := range [][]string{{"1", "2", "3"}, {"1", "4"}} {
+		var text []string
+		for _, block := range strings.Split(groupDump, "\n\n") {
+			for _, gid := range gids {
+				if strings.HasPrefix(block, "goroutine "+gid+" ") {
+					text = append(text, block)
+				}
+			}
+		}
+		p := NewDump()
+		if err := p.ParseData(strings.Join(text, "\n\n") + "\n"); err != nil {
+			t.Fatal(err)
+		}
+		ps = append(ps, p)
+	}
+	groups := Merge(ps).Regroup(Grouping{Strategy: GroupFunctions})
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(groups))
+	}
+	for _, tf := range groups {
+		if tf.Count != 5 || !reflect.DeepEqual(tf.Instances, []int{3, 2}) {
+			t.Errorf("got %d goroutines on instances %v, want 5 on [3 2]", tf.Count, tf.Instances)
+		}
+	}
+}
+
+func TestGroupingCheck(t *testing.T) {
+	for _, tc := range []struct {
+		grouping Grouping
+		ok       bool
+	}{
+		{Grouping{Strategy: GroupPackage}, true},
+		{Grouping{Strategy: GroupBottom, Frames: 3}, true},
+		{Grouping{Strategy: GroupTop}, false},
+		{Grouping{Strategy: "stack"}, false},
+	} {
+		if err := tc.grouping.Check(); (err == nil) != tc.ok {
+			t.Errorf("%+v: got error %v", tc.grouping, err)
+		}
+	}
+}
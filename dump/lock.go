@@ -0,0 +1,361 @@
+package dump
+
+import (
+	"sort"
+	"strings"
+)
+
+// Lock kinds, named after the wait reasons of the runtime.
+const (
+	MutexLock    = "sync.Mutex.Lock"
+	RWMutexLock  = "sync.RWMutex.Lock"
+	RWMutexRLock = "sync.RWMutex.RLock"
+)
+
+// States of a goroutine waiting for a lock.
+const (
+	WaitUnlock  = "unlock"  // waits for a Mutex, or the writer lock of an RWMutex, to be unlocked
+	WaitReaders = "readers" // writer of an RWMutex waits for its readers, and holds off new ones
+	WaitWriter  = "writer"  // reader of an RWMutex waits for a pending or active writer
+)
+
+// isSemacquire reports whether s is a call of the runtime acquiring a
+// semaphore, whose first argument is the address of the semaphore. The
+// calls of sync print stale arguments since Go 1.17, but runtime.semacquire1
+// spills them.
+func (s *Stack) isSemacquire() bool {
+	return s.FuncName == "runtime.semacquire1" || strings.HasPrefix(s.Method, "runtime_Semacquire")
+}
+
+// LockWait is a goroutine blocked acquiring a lock.
+type LockWait struct {
+	GID   int
+	Kind  string // MutexLock, RWMutexLock, RWMutexRLock, or the kind of a LockPattern
+	State string // WaitUnlock, WaitReaders or WaitWriter
+	Addr  uint64 // address of the lock, or of its owner if not printed, 0 if unknown
+	Owner uint64 // address of the object whose method acquires the lock, 0 if unknown
+	Stack int    // index of the call acquiring the lock in the stacks of the goroutine
+
+	ByOwner bool // Addr is the owner of the lock, as the lock was not printed
+}
+
+// LockHold is a goroutine that probably holds a lock, as it was passed
+// the lock or the object whose methods acquire it, or is releasing it,
+// and does not wait for it.
+type LockHold struct {
+	GID   int
+	Stack int  // index of the call passed the lock or its owner
+	Stale bool // whether it was passed in a word that may be stale
+}
+
+// WaitGraph is the graph of goroutines waiting for locks held by other
+// goroutines.
+type WaitGraph struct {
+	Waits   map[int]*LockWait     // goroutines blocked acquiring a lock
+	Holders map[uint64][]LockHold // probable holders of each lock waited on
+}
+
+// decodeLockWait returns the lock f is blocked acquiring, as recognised
+// by patterns, or nil.
+func (f *Frame) decodeLockWait(patterns []LockPattern) *LockWait {
+	if f.Reason == "running" || f.Reason == "runnable" {
+		return nil
+	}
+	// Skip the runtime functions parking the goroutine.
+	i := 0
+	var sema uint64
+	for ; i < len(f.Stacks); i++ {
+		s := &f.Stacks[i]
+		if s.isSemacquire() && sema == 0 && len(s.Args) > 0 && !s.Args[0].Unsure {
+			sema = s.Args[0].Value
+		}
+		if s.ImportPath != "runtime" && !strings.HasPrefix(s.Method, "runtime_") {
+			break
+		}
+	}
+	w := &LockWait{GID: f.GID, State: WaitUnlock}
+	var inner *LockPattern
+	for ; i < len(f.Stacks); i++ {
+		s := &f.Stacks[i]
+		l := s.lockPattern(patterns, false)
+		if l == nil {
+			break
+		}
+		w.Stack = i
+		if l.Kind == "" {
+			continue
+		}
+		if inner == nil {
+			inner = l
+		}
+		w.Kind = l.Kind
+		if addr, ok := s.lockAddr(l); ok {
+			w.Addr = addr
+		}
+	}
+	if inner == nil {
+		return nil
+	}
+	switch inner.Kind {
+	case RWMutexLock:
+		w.State = WaitReaders
+	case RWMutexRLock:
+		w.State = WaitWriter
+	}
+	if w.Addr == 0 && inner.sema > 0 && sema > inner.sema {
+		w.Addr = sema - inner.sema
+	}
+	if i < len(f.Stacks) {
+		w.Owner, _ = f.Stacks[i].Object()
+	}
+	// A lock acquired by inlined calls is told by its owner.
+	if w.Addr == 0 && w.Owner != 0 {
+		w.Addr, w.ByOwner = w.Owner, true
+	}
+	return w
+}
+
+// WaitGraph builds the graph of goroutines waiting for locks, and of
+// the goroutines that probably hold them.
+func (p *Dump) WaitGraph() *WaitGraph {
+	g := &WaitGraph{
+		Waits:   make(map[int]*LockWait),
+		Holders: make(map[uint64][]LockHold),
+	}
+	patterns := p.lockPatterns()
+	owners := make(map[uint64]map[uint64]bool) // owners of each lock
+	releases := make(map[uint64][]Occurrence)  // calls releasing each lock
+	for _, f := range p.RawFrames {
+		for i := range f.Stacks {
+			s := &f.Stacks[i]
+			if l := s.lockPattern(patterns, true); l != nil {
+				if addr, ok := s.lockAddr(l); ok {
+					releases[addr] = append(releases[addr], Occurrence{GID: f.GID, Stack: i})
+				}
+			}
+		}
+		if w := f.decodeLockWait(patterns); w != nil && w.Addr != 0 {
+			g.Waits[f.GID] = w
+			if owners[w.Addr] == nil {
+				owners[w.Addr] = make(map[uint64]bool)
+			}
+			if w.Owner != 0 {
+				owners[w.Addr][w.Owner] = true
+			}
+		}
+	}
+	for lock, objects := range owners {
+		seen := make(map[int]bool)
+		addrs := []uint64{lock}
+		for owner := range objects {
+			addrs = append(addrs, owner)
+		}
+		for _, addr := range addrs {
+			occurrences := append(append([]Occurrence(nil), releases[addr]...), p.Addresses[addr]...)
+			for _, o := range occurrences {
+				if w := g.Waits[o.GID]; seen[o.GID] || w != nil && w.Addr == lock {
+					continue
+				}
+				seen[o.GID] = true
+				g.Holders[lock] = append(g.Holders[lock], LockHold{GID: o.GID, Stack: o.Stack, Stale: o.Stale})
+			}
+		}
+		sort.Slice(g.Holders[lock], func(i, j int) bool {
+			return g.Holders[lock][i].GID < g.Holders[lock][j].GID
+		})
+	}
+	return g
+}
+
+// edges returns the goroutines gid waits for.
+func (g *WaitGraph) edges(gid int) []int {
+	w := g.Waits[gid]
+	if w == nil {
+		return nil
+	}
+	var gids []int
+	for _, h := range g.Holders[w.Addr] {
+		// Deadlocks are only reported on words known not to be stale.
+		if !h.Stale {
+			gids = append(gids, h.GID)
+		}
+	}
+	return gids
+}
+
+// waitsFor reports whether goroutine gid waits for goroutine holder.
+func (g *WaitGraph) waitsFor(gid, holder int) bool {
+	for _, h := range g.edges(gid) {
+		if h == holder {
+			return true
+		}
+	}
+	return false
+}
+
+// maxCycles bounds the number of cycles enumerated, as their number may
+// grow exponentially with the goroutines waiting for each other.
+const maxCycles = 1000
+
+// Cycles returns the cycles of goroutines waiting for each other, each
+// starting with its lowest goroutine, which are probable deadlocks. A
+// goroutine among the holders of the lock it waits for, as a recursive
+// lock makes it, is a cycle of its own.
+func (g *WaitGraph) Cycles() [][]int {
+	var cycles [][]int
+	for _, scc := range g.components() {
+		cycles = append(cycles, g.cycles(scc, maxCycles-len(cycles))...)
+	}
+	return cycles
+}
+
+// components returns the strongly connected components of the graph
+// with more than one goroutine, or a goroutine waiting for itself, found
+// by Tarjan's algorithm.
+func (g *WaitGraph) components() [][]int {
+	var gids []int
+	for gid := range g.Waits {
+		gids = append(gids, gid)
+	}
+	sort.Ints(gids)
+
+	index := make(map[int]int)
+	low := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var sccs [][]int
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.edges(v) {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 || g.waitsFor(v, v) {
+			sort.Ints(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, v := range gids {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+	sort.Slice(sccs, func(i, j int) bool { return sccs[i][0] < sccs[j][0] })
+	return sccs
+}
+
+// cycles returns up to max elementary cycles of the strongly connected
+// component scc. Each cycle is found once, from its lowest goroutine.
+func (g *WaitGraph) cycles(scc []int, max int) [][]int {
+	in := make(map[int]bool, len(scc))
+	for _, gid := range scc {
+		in[gid] = true
+	}
+	var cycles [][]int
+	for _, start := range scc {
+		var path []int
+		onPath := make(map[int]bool)
+		var visit func(v int)
+		visit = func(v int) {
+			path = append(path, v)
+			onPath[v] = true
+			for _, w := range g.edges(v) {
+				if len(cycles) >= max {
+					break
+				}
+				switch {
+				case w == start:
+					cycles = append(cycles, append([]int(nil), path...))
+				case in[w] && w > start && !onPath[w]:
+					visit(w)
+				}
+			}
+			path = path[:len(path)-1]
+			onPath[v] = false
+		}
+		visit(start)
+	}
+	return cycles
+}
+
+// Contention is a lock goroutines wait for, with its probable holders.
+type Contention struct {
+	Addr    uint64
+	Kind    string      // kind of lock the first waiter acquires
+	Waiters []*LockWait // goroutines waiting for the lock, ordered by GID
+	Holders []LockHold  // probable holders, the most probable first
+}
+
+// holderRank ranks a probable holder of a lock: goroutines doing work,
+// such as running or waiting for a syscall or IO, are the most likely to
+// hold a lock for long, and goroutines waiting for another lock are the
+// least likely.
+func holderRank(f *Frame, g *WaitGraph) int {
+	switch {
+	case f == nil:
+		return 3
+	case f.Reason == "running" || f.Reason == "runnable" ||
+		f.Reason == "syscall" || f.Reason == "IO wait":
+		return 0
+	case g.Waits[f.GID] == nil:
+		return 1
+	}
+	return 2
+}
+
+// Contentions returns the locks goroutines wait for in g, the wait graph
+// of p, the most waited for first, with their probable holders ranked.
+func (p *Dump) Contentions(g *WaitGraph) []*Contention {
+	locks := make(map[uint64]*Contention)
+	for _, w := range g.Waits {
+		c := locks[w.Addr]
+		if c == nil {
+			c = &Contention{Addr: w.Addr, Holders: g.Holders[w.Addr]}
+			locks[w.Addr] = c
+		}
+		c.Waiters = append(c.Waiters, w)
+	}
+	contentions := make([]*Contention, 0, len(locks))
+	for _, c := range locks {
+		sort.Slice(c.Waiters, func(i, j int) bool { return c.Waiters[i].GID < c.Waiters[j].GID })
+		c.Kind = c.Waiters[0].Kind
+		sort.SliceStable(c.Holders, func(i, j int) bool {
+			hi, hj := c.Holders[i], c.Holders[j]
+			if hi.Stale != hj.Stale {
+				return hj.Stale
+			}
+			return holderRank(p.GetFrameByGID(hi.GID), g) < holderRank(p.GetFrameByGID(hj.GID), g)
+		})
+		contentions = append(contentions, c)
+	}
+	sort.Slice(contentions, func(i, j int) bool {
+		if len(contentions[i].Waiters) != len(contentions[j].Waiters) {
+			return len(contentions[i].Waiters) > len(contentions[j].Waiters)
+		}
+		return contentions[i].Addr < contentions[j].Addr
+	})
+	return contentions
+}
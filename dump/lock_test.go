@@ -0,0 +1,118 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// waitGraph returns a wait graph where each goroutine of edges waits for
+// a lock of its own, held by the goroutines it maps to.
+func waitGraph(edges map[int][]int) *WaitGraph {
+	g := &WaitGraph{
+		Waits:   make(map[int]*LockWait),
+		Holders: make(map[uint64][]LockHold),
+	}
+	for gid, holders := range edges {
+		addr := uint64(0x1000 * gid)
+		g.Waits[gid] = &LockWait{GID: gid, Kind: MutexLock, State: WaitUnlock, Addr: addr}
+		for _, h := range holders {
+			g.Holders[addr] = append(g.Holders[addr], LockHold{GID: h})
+		}
+	}
+	return g
+}
+
+func TestCycles(t *testing.T) {
+	// Every goroutine of 7 waits for every other one, which makes more
+	// elementary cycles than are enumerated.
+	complete := make(map[int][]int)
+	for i := 1; i <= 7; i++ {
+		for j := 1; j <= 7; j++ {
+			if i != j {
+				complete[i] = append(complete[i], j)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		edges map[int][]int
+		want  [][]int
+	}{
+		{"two goroutines", map[int][]int{5: {6}, 6: {5}}, [][]int{{5, 6}}},
+		{"three goroutines", map[int][]int{7: {5}, 5: {6}, 6: {7}}, [][]int{{5, 6, 7}}},
+		// Goroutine 5 locks a lock it holds again.
+		{"recursive lock", map[int][]int{5: {5}}, [][]int{{5}}},
+		{"disjoint cycles", map[int][]int{1: {2}, 2: {1}, 3: {4}, 4: {5}, 5: {3}}, [][]int{{1, 2}, {3, 4, 5}}},
+		{"no cycle", map[int][]int{1: {2}, 2: {3}, 4: {3}}, nil},
+		// Goroutine 3 waits in the cycle of 1 and 2 without being part
+		// of it.
+		{"waiter of a cycle", map[int][]int{1: {2}, 2: {1}, 3: {1}}, [][]int{{1, 2}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := waitGraph(tc.edges).Cycles(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got cycles %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("capped", func(t *testing.T) {
+		cycles := waitGraph(complete).Cycles()
+		if len(cycles) != maxCycles {
+			t.Fatalf("got %d cycles, want %d", len(cycles), maxCycles)
+		}
+		seen := make(map[string]bool)
+		for _, c := range cycles {
+			key := fmt.Sprint(c)
+			if seen[key] {
+				t.Fatalf("cycle %v found twice", c)
+			}
+			seen[key] = true
+			for _, gid := range c[1:] {
+				if gid <= c[0] {
+					t.Fatalf("cycle %v does not start with its lowest goroutine", c)
+				}
+			}
+		}
+	})
+}
+
+func TestStaleHoldersMakeNoCycle(t *testing.T) {
+	g := waitGraph(map[int][]int{5: {6}, 6: {5}})
+	g.Holders[0x5000][0].Stale = true
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("got cycles %v through a stale holder, want none", cycles)
+	}
+}
+
+func TestWaitGraphCycle(t *testing.T) {
+	// Goroutines 5 and 6 each hold the Mutex of the account they
+	// transfer from, and wait for that of the other.
+	p := NewDump()
+	if err := p.ParseData(`goroutine 5 [sync.Mutex.Lock, 3 minutes]:
+internal/sync.runtime_SemacquireMutex(0xc000020008?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+sync.(*Mutex).Lock(0xc000020000)
+	/usr/local/go/src/sync/mutex.go:46 +0x48
+main.(*Account).Deposit(0xc000020000, 0x1)
+	/src/app/main.go:12 +0x45
+main.(*Account).Transfer(0xc000010000, 0xc000020000, 0x1)
+	/src/app/main.go:18 +0x85
+
+goroutine 6 [sync.Mutex.Lock, 3 minutes]:
+internal/sync.runtime_SemacquireMutex(0xc000010008?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+sync.(*Mutex).Lock(0xc000010000)
+	/usr/local/go/src/sync/mutex.go:46 +0x48
+main.(*Account).Deposit(0xc000010000, 0x1)
+	/src/app/main.go:12 +0x45
+main.(*Account).Transfer(0xc000020000, 0xc000010000, 0x1)
+	/src/app/main.go:18 +0x85
+`); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.WaitGraph().Cycles(), [][]int{{5, 6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cycles %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// LockPattern recognises the calls acquiring or releasing a lock, read
+// from a JSON file such as
+//
+//	[
+//	  {"func": "example\\.com/kvlock\\.\\(\\*Lock\\)\\.Acquire", "kind": "kvlock.Lock"},
+//	  {"func": "example\\.com/kvlock\\.\\(\\*Lock\\)\\.Release", "release": true}
+//	]
+//
+// Function names are matched without the prefix of vendored packages.
+type LockPattern struct {
+	Func    string `json:"func"`    // regexp matching the whole function name
+	Kind    string `json:"kind"`    // kind of lock acquired, or "" for calls made acquiring one
+	Arg     int    `json:"arg"`     // index of the argument word holding the lock, -1 for none
+	Release bool   `json:"release"` // whether the call releases the lock instead
+
+	sema   uint64 // offset in the lock of the semaphore the call blocks on
+	re     *regexp.Regexp
+	prefix string // literal prefix of the names re matches
+}
+
+// compile compiles the function regexp of p.
+func (p *LockPattern) compile() error {
+	re, err := regexp.Compile("^(?:" + p.Func + ")$")
+	if err != nil {
+		return fmt.Errorf("lock pattern %q: %v", p.Func, err)
+	}
+	p.re = re
+	p.prefix, _ = re.LiteralPrefix()
+	return nil
+}
+
+// stdLock returns a pattern for a method of the standard library.
+func stdLock(name, kind string, sema uint64) LockPattern {
+	return LockPattern{Func: regexp.QuoteMeta(name), Kind: kind, sema: sema}
+}
+
+// lockCall returns a pattern for a method acquiring a lock.
+func lockCall(name, kind string) LockPattern {
+	return LockPattern{Func: regexp.QuoteMeta(name), Kind: kind}
+}
+
+// innerCall returns a pattern for a function called acquiring a lock,
+// which is told by the calls further out.
+func innerCall(name string) LockPattern {
+	return LockPattern{Func: regexp.QuoteMeta(name), Arg: -1}
+}
+
+// unlockCall returns a pattern for a method releasing a lock.
+func unlockCall(name string) LockPattern {
+	return LockPattern{Func: regexp.QuoteMeta(name), Release: true}
+}
+
+// builtinLockPatterns are the lock patterns of the standard library and
+// of popular libraries. sync.Mutex wraps internal/sync.Mutex since Go
+// 1.24, and sync.RWMutex.Lock acquires the sync.Mutex at the start of
+// the RWMutex, so a lock is told by the outermost of the calls acquiring
+// it, and the semaphore waited on by the innermost.
+var builtinLockPatterns = []LockPattern{
+	stdLock("sync.(*Mutex).Lock", MutexLock, 4), // Mutex.sema
+	stdLock("sync.(*Mutex).lockSlow", MutexLock, 4),
+	stdLock("internal/sync.(*Mutex).Lock", MutexLock, 4),
+	stdLock("internal/sync.(*Mutex).lockSlow", MutexLock, 4),
+	stdLock("sync.(*RWMutex).Lock", RWMutexLock, 8),    // RWMutex.writerSem
+	stdLock("sync.(*RWMutex).RLock", RWMutexRLock, 12), // RWMutex.readerSem
+	stdLock("sync.(*rlocker).Lock", RWMutexRLock, 12),  // RWMutex.RLocker
+	unlockCall("sync.(*Mutex).Unlock"),
+	unlockCall("sync.(*Mutex).unlockSlow"),
+	unlockCall("internal/sync.(*Mutex).Unlock"),
+	unlockCall("internal/sync.(*Mutex).unlockSlow"),
+	unlockCall("sync.(*RWMutex).Unlock"),
+	unlockCall("sync.(*RWMutex).RUnlock"),
+	unlockCall("sync.(*RWMutex).rUnlockSlow"),
+	unlockCall("sync.(*rlocker).Unlock"),
+
+	// github.com/sasha-s/go-deadlock wraps the locks of sync.
+	lockCall("github.com/sasha-s/go-deadlock.(*Mutex).Lock", MutexLock),
+	lockCall("github.com/sasha-s/go-deadlock.(*RWMutex).Lock", RWMutexLock),
+	lockCall("github.com/sasha-s/go-deadlock.(*RWMutex).RLock", RWMutexRLock),
+	innerCall("github.com/sasha-s/go-deadlock.lock"),
+	unlockCall("github.com/sasha-s/go-deadlock.(*Mutex).Unlock"),
+	unlockCall("github.com/sasha-s/go-deadlock.(*RWMutex).Unlock"),
+	unlockCall("github.com/sasha-s/go-deadlock.(*RWMutex).RUnlock"),
+
+	// github.com/gofrs/flock blocks in flock(2).
+	lockCall("github.com/gofrs/flock.(*Flock).Lock", "flock.Lock"),
+	lockCall("github.com/gofrs/flock.(*Flock).RLock", "flock.RLock"),
+	lockCall("github.com/gofrs/flock.(*Flock).lock", "flock.Lock"),
+	innerCall("syscall.Flock"),
+	innerCall("syscall.Syscall"),
+	innerCall("syscall.Syscall6"),
+	innerCall("syscall.RawSyscall6"),
+	innerCall("golang.org/x/sys/unix.Flock"),
+	innerCall("golang.org/x/sys/unix.Syscall"),
+	innerCall("internal/runtime/syscall.Syscall6"),
+	innerCall("internal/runtime/syscall/linux.Syscall6"),
+	unlockCall("github.com/gofrs/flock.(*Flock).Unlock"),
+
+	// Distributed locks of etcd, waiting for the deletion of the keys
+	// of the sessions locking before.
+	lockCall("go.etcd.io/etcd/client/v3/concurrency.(*Mutex).Lock", "etcd.Mutex.Lock"),
+	lockCall("go.etcd.io/etcd/clientv3/concurrency.(*Mutex).Lock", "etcd.Mutex.Lock"),
+	lockCall("github.com/coreos/etcd/clientv3/concurrency.(*Mutex).Lock", "etcd.Mutex.Lock"),
+	innerCall("go.etcd.io/etcd/client/v3/concurrency.waitDeletes"),
+	innerCall("go.etcd.io/etcd/client/v3/concurrency.waitDelete"),
+	innerCall("go.etcd.io/etcd/clientv3/concurrency.waitDeletes"),
+	innerCall("go.etcd.io/etcd/clientv3/concurrency.waitDelete"),
+	innerCall("github.com/coreos/etcd/clientv3/concurrency.waitDeletes"),
+	innerCall("github.com/coreos/etcd/clientv3/concurrency.waitDelete"),
+	unlockCall("go.etcd.io/etcd/client/v3/concurrency.(*Mutex).Unlock"),
+	unlockCall("go.etcd.io/etcd/clientv3/concurrency.(*Mutex).Unlock"),
+	unlockCall("github.com/coreos/etcd/clientv3/concurrency.(*Mutex).Unlock"),
+}
+
+func init() {
+	for i := range builtinLockPatterns {
+		if err := builtinLockPatterns[i].compile(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ReadLockPatterns reads a JSON array of lock patterns.
+func ReadLockPatterns(r io.Reader) ([]LockPattern, error) {
+	var patterns []LockPattern
+	if err := json.NewDecoder(r).Decode(&patterns); err != nil {
+		return nil, fmt.Errorf("cannot read lock patterns: %v", err)
+	}
+	for i := range patterns {
+		if err := patterns[i].compile(); err != nil {
+			return nil, err
+		}
+		if !patterns[i].Release && patterns[i].Kind == "" && patterns[i].Arg >= 0 {
+			return nil, fmt.Errorf("lock pattern %q: a call acquiring a lock needs its kind, or arg -1 if it is made acquiring one", patterns[i].Func)
+		}
+	}
+	return patterns, nil
+}
+
+// samePatterns reports whether a and b are the same lock patterns.
+func samePatterns(a, b []LockPattern) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Func != b[i].Func || a[i].Kind != b[i].Kind || a[i].Arg != b[i].Arg || a[i].Release != b[i].Release {
+			return false
+		}
+	}
+	return true
+}
+
+// lockPattern returns the first of patterns matching the function of s
+// that acquires a lock, or releases one if release is set.
+func (s *Stack) lockPattern(patterns []LockPattern, release bool) *LockPattern {
+	name := importPath(s.FuncName)
+	for i := range patterns {
+		p := &patterns[i]
+		if p.Release == release && strings.HasPrefix(name, p.prefix) && p.re.MatchString(name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// lockAddr returns the address of the lock in the arguments of s, as
+// located by p.
+func (s *Stack) lockAddr(p *LockPattern) (uint64, bool) {
+	if p.Arg < 0 || p.Arg >= len(s.Args) {
+		return 0, false
+	}
+	w := s.Args[p.Arg]
+	if w.Unsure || w.Value < minAddress {
+		return 0, false
+	}
+	return w.Value, true
+}
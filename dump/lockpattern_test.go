@@ -0,0 +1,63 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetOptionsLockPatterns(t *testing.T) {
+	const text = `goroutine 5 [select, 20 minutes]:
+example.com/kvlock.(*Lock).Acquire(0xc000100000, 0x1)
+	/src/kvlock/lock.go:40 +0x5d
+main.(*Server).handle(0xc000110000)
+	/src/app/main.go:20 +0x3c
+
+goroutine 6 [select, 20 minutes]:
+example.com/kvlock.(*Lock).Acquire(0xc000100000, 0x1)
+	/src/kvlock/lock.go:40 +0x5d
+main.(*Server).handle(0xc000110000)
+	/src/app/main.go:20 +0x3c
+`
+	patterns, err := ReadLockPatterns(strings.NewReader(`[
+		{"func": "example\\.com/kvlock\\.\\(\\*Lock\\)\\.Acquire", "kind": "kvlock.Lock"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(p *Dump, kind string, waiters int) {
+		t.Helper()
+		for _, f := range p.RawFrames {
+			if f.LockType != kind {
+				t.Errorf("goroutine %d: got lock %q, want %q", f.GID, f.LockType, kind)
+			}
+		}
+		for key, tf := range p.TrimedFrames {
+			if tf.LockType != kind {
+				t.Errorf("group %s: got lock %q, want %q", key, tf.LockType, kind)
+			}
+		}
+		n := 0
+		for _, c := range p.Contentions(p.WaitGraph()) {
+			n += len(c.Waiters)
+		}
+		if n != waiters {
+			t.Errorf("got %d goroutines waiting for locks, want %d", n, waiters)
+		}
+	}
+
+	p := NewDump()
+	if err := p.ParseData(text); err != nil {
+		t.Fatal(err)
+	}
+	check(p, "", 0)
+	p.SetOptions(Options{LockPatterns: patterns})
+	check(p, "kvlock.Lock", 2)
+	p.SetOptions(Options{})
+	check(p, "", 0)
+
+	ps, err := ParseSnapshots(strings.NewReader(text), Options{LockPatterns: patterns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(ps[0], "kvlock.Lock", 2)
+}
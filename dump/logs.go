@@ -0,0 +1,125 @@
+package dump
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dumps are often collected through log pipelines, which prefix each
+// line of a dump with their own metadata, or carry a whole dump in a
+// single JSON string. The text decoder unwraps the lines of a dump from
+// the log lines holding them, recognizing:
+//
+//	Dec 01 08:42:12 host dockerd[2431]: goroutine 1 [running]:
+//	2021-12-01T08:42:12.123456789Z goroutine 1 [running]:
+//	2021-12-01T08:42:12.123456789Z stderr F goroutine 1 [running]:
+//	{"log":"goroutine 1 [running]:\n","stream":"stderr","time":"2021-12-01T08:42:12Z"}
+//	{"level":"error","stack":"goroutine 1 [running]:\nmain.main()\n..."}
+//
+// that is journald, kubectl logs --timestamps, CRI and docker json-file
+// logs, and stacks embedded in structured logs. Prefixes are recognized
+// line by line, so a dump interleaved with other logs is still read.
+
+// Dec 01 08:42:12 host dockerd[2431]:
+var journalRE = regexp.MustCompile(`^(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ 0-9]\d \d\d:\d\d:\d\d \S+ [^\s:]+: `)
+
+// 2021-12-01T08:42:12.123456789Z
+var timestampRE = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:\d\d) `)
+
+// stderr F
+var criRE = regexp.MustCompile(`^(?:stdout|stderr) ([FP]) `)
+
+// logUnwrapper unwraps the lines of a dump from log lines.
+type logUnwrapper struct {
+	partial string // start of a line split over several log lines
+}
+
+// unwrap returns the lines of a dump held by a log line, or no line if
+// the log line holds the start of a longer line.
+func (u *logUnwrapper) unwrap(line string) []string {
+	if len(line) == 0 {
+		return u.complete(line)
+	}
+	if journalRE.MatchString(line) {
+		line = line[len(journalRE.FindString(line)):]
+	}
+	if timestampRE.MatchString(line) {
+		line = line[len(timestampRE.FindString(line)):]
+		if m := criRE.FindStringSubmatch(line); m != nil {
+			line = line[len(m[0]):]
+			if m[1] == "P" {
+				u.partial += line
+				return nil
+			}
+		}
+	}
+	if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+		if lines, ok := u.unwrapJSON(line); ok {
+			return lines
+		}
+	}
+	return u.complete(line)
+}
+
+// complete ends the line started by partial log lines with line.
+func (u *logUnwrapper) complete(line string) []string {
+	if u.partial != "" {
+		line, u.partial = u.partial+line, ""
+	}
+	return []string{line}
+}
+
+// unwrapJSON returns the lines of a dump held by a JSON log line: the
+// "log" field of docker json-file logs, or a string field holding a
+// whole stack.
+func (u *logUnwrapper) unwrapJSON(line string) ([]string, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, false
+	}
+	if log, ok := fields["log"].(string); ok {
+		// Docker splits long lines over several log lines, only the
+		// last of which ends with a newline.
+		if !strings.HasSuffix(log, "\n") {
+			u.partial += log
+			return nil, true
+		}
+		return u.complete(strings.TrimRight(log, "\r\n")), true
+	}
+	stack, ok := findStack(fields)
+	if !ok {
+		return nil, false
+	}
+	lines := strings.Split(strings.TrimRight(stack, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	// End the last goroutine of the stack, as the next log line is
+	// unrelated to it.
+	return append(lines, ""), true
+}
+
+// findStack returns the first string of fields, or of objects nested in
+// fields, that holds goroutines.
+func findStack(fields map[string]interface{}) (string, bool) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case string:
+			if strings.Contains(v, "goroutine ") && strings.Contains(v, "\n") {
+				return v, true
+			}
+		case map[string]interface{}:
+			if s, ok := findStack(v); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
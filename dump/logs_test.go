@@ -0,0 +1,32 @@
+package dump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLogs(t *testing.T) {
+	want := parseFile(t, "go1.21.txt")
+	for _, file := range []string{
+		"go1.21.journald.txt",
+		"go1.21.timestamps.txt",
+		// Lines split into CRI partial log lines.
+		"go1.21.cri.txt",
+		// Lines split over several docker json-file log lines.
+		"go1.21.docker.json",
+		// The dump held by a field of a structured log line.
+		"go1.21.structured.json",
+		// Goroutines interleaved with other logs, some CRI.
+		"go1.21.mixed.txt",
+	} {
+		t.Run(file, func(t *testing.T) {
+			p := parseFile(t, file)
+			if !reflect.DeepEqual(p.RawFrames, want.RawFrames) {
+				t.Errorf("got goroutines %+v, want those of go1.21.txt %+v", p.RawFrames, want.RawFrames)
+			}
+			if !reflect.DeepEqual(p.TrimedFrames, want.TrimedFrames) {
+				t.Errorf("got groups %+v, want those of go1.21.txt %+v", p.TrimedFrames, want.TrimedFrames)
+			}
+		})
+	}
+}
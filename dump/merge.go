@@ -0,0 +1,61 @@
+package dump
+
+import "fmt"
+
+// Merge merges the dumps of instances of a program, such as the replicas
+// of a service, into a fleet-wide dump whose groups count the goroutines
+// of each instance. Goroutine IDs and addresses are only meaningful in
+// the instance they were dumped from, so the merged dump keeps the
+// instances but has no goroutine of its own: goroutines are looked up,
+// and locks and channels analysed, per instance.
+func Merge(ps []*Dump) *Dump {
+	m := NewDump()
+	if len(ps) > 0 {
+		m.SetOptions(ps[0].options)
+	}
+	m.Instances = ps
+	m.Source = Source{Name: fmt.Sprintf("%d instances", len(ps))}
+	if len(ps) == 1 {
+		m.Source.Name = ps[0].Source.Name
+	}
+	for i, p := range ps {
+		for _, key := range sortedKeys(p.TrimedFrames) {
+			tf := p.TrimedFrames[key]
+			mkey := m.genTrimedKey(&tf.Frame, 0)
+			mf, ok := m.TrimedFrames[mkey]
+			if !ok {
+				mf = TrimedFrame{Frame: tf.Frame, Instances: make([]int, len(ps))}
+			}
+			for _, h := range tf.Heads {
+				h.Instance = i + 1
+				mf.Heads = append(mf.Heads, h)
+			}
+			mf.Instances[i] += tf.Count
+			mf.Count += tf.Count
+			if minutes := tf.Minutes(); minutes > mf.Duration {
+				mf.Duration = minutes
+			}
+			m.TrimedFrames[mkey] = mf
+		}
+		for reason, n := range p.Surmary {
+			m.Surmary[reason] += n
+		}
+	}
+	return m
+}
+
+// Merged reports whether p merges the dumps of several instances.
+func (p *Dump) Merged() bool {
+	return p.Instances != nil
+}
+
+// Spread returns the number of merged instances tf has goroutines on.
+func (tf *TrimedFrame) Spread() int {
+	n := 0
+	for _, count := range tf.Instances {
+		if count > 0 {
+			n++
+		}
+	}
+	return n
+}
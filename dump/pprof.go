@@ -0,0 +1,318 @@
+package dump
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The messages of profile.proto needed to read goroutine profiles as
+// saved by /debug/pprof/goroutine and go tool pprof. Each sample of a
+// goroutine profile stands for the goroutines sharing its stack.
+
+type pbProfile struct {
+	sampleTypes []*pbValueType
+	samples     []*pbSample
+	locations   []*pbLocation
+	functions   []*pbFunction
+	strings     []string
+}
+
+type pbValueType struct {
+	typ  int64
+	unit int64
+}
+
+type pbSample struct {
+	locationIDs []uint64
+	values      []int64
+	labels      []*pbSampleLabel
+}
+
+type pbSampleLabel struct {
+	key     int64
+	str     int64
+	num     int64
+	numUnit int64
+}
+
+type pbLocation struct {
+	id    uint64
+	lines []*pbLine
+}
+
+type pbLine struct {
+	functionID uint64
+	line       int64
+}
+
+type pbFunction struct {
+	id       uint64
+	name     int64
+	filename int64
+}
+
+func (p *pbProfile) decoder() []decoder {
+	return profileDecoder
+}
+
+var profileDecoder = []decoder{
+	nil, // 0
+	// repeated ValueType sample_type = 1
+	func(b *buffer, m message) error {
+		x := new(pbValueType)
+		p := m.(*pbProfile)
+		p.sampleTypes = append(p.sampleTypes, x)
+		return decodeMessage(b, x)
+	},
+	// repeated Sample sample = 2
+	func(b *buffer, m message) error {
+		x := new(pbSample)
+		p := m.(*pbProfile)
+		p.samples = append(p.samples, x)
+		return decodeMessage(b, x)
+	},
+	// repeated Mapping mapping = 3
+	nil,
+	// repeated Location location = 4
+	func(b *buffer, m message) error {
+		x := new(pbLocation)
+		p := m.(*pbProfile)
+		p.locations = append(p.locations, x)
+		return decodeMessage(b, x)
+	},
+	// repeated Function function = 5
+	func(b *buffer, m message) error {
+		x := new(pbFunction)
+		p := m.(*pbProfile)
+		p.functions = append(p.functions, x)
+		return decodeMessage(b, x)
+	},
+	// repeated string string_table = 6
+	func(b *buffer, m message) error { return decodeStrings(b, &m.(*pbProfile).strings) },
+}
+
+func (p *pbValueType) decoder() []decoder {
+	return valueTypeDecoder
+}
+
+var valueTypeDecoder = []decoder{
+	nil, // 0
+	// int64 type = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbValueType).typ) },
+	// int64 unit = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbValueType).unit) },
+}
+
+func (p *pbSample) decoder() []decoder {
+	return sampleDecoder
+}
+
+var sampleDecoder = []decoder{
+	nil, // 0
+	// repeated uint64 location_id = 1
+	func(b *buffer, m message) error { return decodeUint64s(b, &m.(*pbSample).locationIDs) },
+	// repeated int64 value = 2
+	func(b *buffer, m message) error { return decodeInt64s(b, &m.(*pbSample).values) },
+	// repeated Label label = 3
+	func(b *buffer, m message) error {
+		x := new(pbSampleLabel)
+		p := m.(*pbSample)
+		p.labels = append(p.labels, x)
+		return decodeMessage(b, x)
+	},
+}
+
+func (p *pbSampleLabel) decoder() []decoder {
+	return sampleLabelDecoder
+}
+
+var sampleLabelDecoder = []decoder{
+	nil, // 0
+	// int64 key = 1
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSampleLabel).key) },
+	// int64 str = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSampleLabel).str) },
+	// int64 num = 3
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSampleLabel).num) },
+	// int64 num_unit = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbSampleLabel).numUnit) },
+}
+
+func (p *pbLocation) decoder() []decoder {
+	return locationDecoder
+}
+
+var locationDecoder = []decoder{
+	nil, // 0
+	// uint64 id = 1
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbLocation).id) },
+	// uint64 mapping_id = 2
+	nil,
+	// uint64 address = 3
+	nil,
+	// repeated Line line = 4
+	func(b *buffer, m message) error {
+		x := new(pbLine)
+		p := m.(*pbLocation)
+		p.lines = append(p.lines, x)
+		return decodeMessage(b, x)
+	},
+}
+
+func (p *pbLine) decoder() []decoder {
+	return lineDecoder
+}
+
+var lineDecoder = []decoder{
+	nil, // 0
+	// uint64 function_id = 1
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbLine).functionID) },
+	// int64 line = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbLine).line) },
+}
+
+func (p *pbFunction) decoder() []decoder {
+	return functionDecoder
+}
+
+var functionDecoder = []decoder{
+	nil, // 0
+	// uint64 id = 1
+	func(b *buffer, m message) error { return decodeUint64(b, &m.(*pbFunction).id) },
+	// int64 name = 2
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFunction).name) },
+	// int64 system_name = 3
+	nil,
+	// int64 filename = 4
+	func(b *buffer, m message) error { return decodeInt64(b, &m.(*pbFunction).filename) },
+}
+
+// get returns the string at index i of the table of a profile.
+func (p *pbProfile) get(i int64) (string, error) {
+	if i < 0 || i >= int64(len(p.strings)) {
+		return "", fmt.Errorf("malformed profile: string index %d out of range", i)
+	}
+	return p.strings[i], nil
+}
+
+// decodeProfile loads the goroutines of the profile.proto message x
+// into p, one group per sample.
+func decodeProfile(x *pbProfile, p *Dump) error {
+	var err error
+	get := func(i int64) string {
+		s, e := x.get(i)
+		if err == nil {
+			err = e
+		}
+		return s
+	}
+
+	// Goroutine profiles have a single "goroutine" count, but be
+	// lenient with profiles that carry others.
+	value := -1
+	for i, st := range x.sampleTypes {
+		if get(st.typ) == "goroutine" {
+			value = i
+		}
+	}
+	if value < 0 {
+		return fmt.Errorf("not a goroutine profile")
+	}
+
+	functions := make(map[uint64]*pbFunction, len(x.functions))
+	for _, fn := range x.functions {
+		functions[fn.id] = fn
+	}
+	locations := make(map[uint64]*pbLocation, len(x.locations))
+	for _, loc := range x.locations {
+		locations[loc.id] = loc
+	}
+
+	for _, s := range x.samples {
+		if value >= len(s.values) || s.values[value] <= 0 {
+			continue
+		}
+		f := &Frame{}
+		for _, id := range s.locationIDs {
+			loc, ok := locations[id]
+			if !ok {
+				return fmt.Errorf("malformed profile: unknown location %d", id)
+			}
+			// Lines of a location are ordered from the innermost
+			// inlined call, as the stacks of a frame are.
+			for _, line := range loc.lines {
+				fn, ok := functions[line.functionID]
+				if !ok {
+					return fmt.Errorf("malformed profile: unknown function %d", line.functionID)
+				}
+				stack := Stack{
+					FuncName: get(fn.name),
+					Location: get(fn.filename) + ":" + strconv.FormatInt(line.line, 10),
+				}
+				stack.decode()
+				f.Stacks = append(f.Stacks, stack)
+			}
+		}
+		for _, l := range s.labels {
+			if f.Labels == nil {
+				f.Labels = make(map[string]string)
+			}
+			v := get(l.str)
+			if l.str == 0 {
+				v = strconv.FormatInt(l.num, 10) + get(l.numUnit)
+			}
+			f.Labels[get(l.key)] = v
+		}
+		if err != nil {
+			return err
+		}
+		f.Size = len(f.Stacks)
+		f.Reason = waitReason(f.Stacks)
+		f.checkHoldLock(p.lockPatterns())
+		p.InsertCountedFrame(f, int(s.values[value]))
+	}
+	return err
+}
+
+// isProfile reports whether data starts like a profile.proto message:
+// with binary bytes, and fields of profile.proto of the right wire
+// type up to the end of data. Text dumps, even holding control
+// characters, are not taken for profiles.
+func isProfile(data []byte) bool {
+	binary := false
+	for _, c := range data {
+		if c < ' ' && c != '\t' && c != '\n' && c != '\r' {
+			binary = true
+			break
+		}
+	}
+	if !binary {
+		return false
+	}
+	for len(data) > 0 {
+		tag, rest, err := decodeVarint(data)
+		if err != nil {
+			return len(data) < 10 // cut by the end of data
+		}
+		switch field, wire := tag>>3, tag&7; {
+		case wire == 0 && (field >= 7 && field <= 10 || field >= 12 && field <= 14):
+			// drop_frames to duration_nanos, period to default_sample_type
+			if _, data, err = decodeVarint(rest); err != nil {
+				return len(rest) < 10
+			}
+		case wire == 2 && (field >= 1 && field <= 6 || field == 11 || field == 13):
+			// sample_type to string_table, period_type, packed comment
+			n, value, err := decodeVarint(rest)
+			if err != nil {
+				return len(rest) < 10
+			}
+			if n >= uint64(len(value)) {
+				return true
+			}
+			data = value[n:]
+		default:
+			return false
+		}
+	}
+	return true
+}
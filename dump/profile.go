@@ -0,0 +1,91 @@
+package dump
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Aggregated goroutine profiles, as served by
+// /debug/pprof/goroutine?debug=1, group goroutines with identical stacks
+// into records:
+//
+//	goroutine profile: total 7
+//	5 @ 0x43b976 0x406cd6 0x4067dd 0x4b6e3a 0x46ca41
+//	# labels: {"handler":"api"}
+//	#	0x4b6e39	main.worker+0x39	/src/app/main.go:12
+//
+// They print neither goroutine IDs nor wait reasons, so the reason is
+// inferred from the stack. Runtime frames are omitted from records, so
+// the reason of goroutines blocked in the runtime itself, such as on
+// channels, is unknown.
+
+// 5 @ 0x43b976 0x406cd6 0x4067dd 0x4b6e3a 0x46ca41
+var recordRE = regexp.MustCompile(`^(\d+) @( 0x[0-9a-f]+)*$`)
+
+// isRecord reports whether line is the header of a record of an
+// aggregated goroutine profile.
+func isRecord(line string) bool {
+	return recordRE.MatchString(line)
+}
+
+// decodeRecord decodes a record of an aggregated goroutine profile and
+// returns the number of goroutines it stands for.
+func (f *Frame) decodeRecord(lines []string) int {
+	count, _ := strconv.Atoi(recordRE.FindStringSubmatch(lines[0])[1])
+	for _, line := range lines[1:] {
+		switch {
+		case strings.HasPrefix(line, "# labels: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "# labels: ")), &f.Labels)
+		case strings.HasPrefix(line, "#\t"):
+			// #	0x4b6e39	main.worker+0x39	/src/app/main.go:12
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			stack := Stack{FuncName: fields[2], Location: strings.Join(fields[3:], " ")}
+			if i := strings.LastIndex(stack.FuncName, "+0x"); i > 0 {
+				stack.PCOffset, _ = strconv.ParseUint(stack.FuncName[i+len("+0x"):], 16, 64)
+				stack.FuncName = stack.FuncName[:i]
+			}
+			stack.decode()
+			f.Stacks = append(f.Stacks, stack)
+		}
+	}
+	f.Size = len(lines) - 1
+	f.Reason = waitReason(f.Stacks)
+	return count
+}
+
+// waitReasons maps runtime functions a goroutine blocks in to the wait
+// reason the runtime prints for it in tracebacks.
+var waitReasons = []struct {
+	prefix, reason string
+}{
+	{"runtime.chanrecv", "chan receive"},
+	{"runtime.chansend", "chan send"},
+	{"runtime.selectgo", "select"},
+	{"runtime.block", "select (no cases)"},
+	{"sync.runtime_notifyListWait", "sync.Cond.Wait"},
+	{"sync.runtime_Semacquire", "semacquire"},
+	{"internal/sync.runtime_Semacquire", "semacquire"},
+	{"internal/poll.runtime_pollWait", "IO wait"},
+	{"time.Sleep", "sleep"},
+	{"syscall.Syscall", "syscall"},
+	{"syscall.RawSyscall", "syscall"},
+	{"runtime.cgocall", "syscall"},
+	{"runtime/pprof.writeRuntimeProfile", "running"},
+}
+
+// waitReason infers the reason a goroutine waits from its stack.
+func waitReason(stacks []Stack) string {
+	for _, stack := range stacks {
+		for _, r := range waitReasons {
+			if strings.HasPrefix(stack.FuncName, r.prefix) {
+				return r.reason
+			}
+		}
+	}
+	return "unknown"
+}
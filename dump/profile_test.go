@@ -0,0 +1,130 @@
+package dump
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDebug1(t *testing.T) {
+	p := parseFile(t, "debug1.txt")
+	if len(p.RawFrames) != 0 {
+		t.Errorf("got %d goroutines with IDs, want none", len(p.RawFrames))
+	}
+	for _, want := range []struct {
+		key    string
+		count  int
+		stacks int
+		labels map[string]string
+	}{
+		{"chan receive_0", 5, 2, nil},
+		{"semacquire_0", 3, 3, map[string]string{"handler": "api"}},
+		{"running_0", 1, 4, nil},
+		{"sleep_0", 1, 2, map[string]string{"handler": "metrics", "shard": "2"}},
+	} {
+		tf, ok := p.TrimedFrames[want.key]
+		if !ok {
+			t.Errorf("no group %s", want.key)
+			continue
+		}
+		if tf.Count != want.count || len(tf.Stacks) != want.stacks {
+			t.Errorf("%s: got %d goroutines and %d frames, want %d and %d", want.key, tf.Count, len(tf.Stacks), want.count, want.stacks)
+		}
+		if len(tf.Labels) != 0 || len(want.labels) != 0 {
+			if !reflect.DeepEqual(tf.Labels, want.labels) {
+				t.Errorf("%s: got labels %v, want %v", want.key, tf.Labels, want.labels)
+			}
+		}
+		if got := p.Surmary[tf.Reason]; got != int64(want.count) {
+			t.Errorf("%s: got %d goroutines in summary, want %d", want.key, got, want.count)
+		}
+	}
+	if len(p.TrimedFrames) != 4 {
+		t.Errorf("got %d groups, want 4", len(p.TrimedFrames))
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	p := parseFile(t, "goroutine.pb.gz")
+	if len(p.RawFrames) != 0 {
+		t.Errorf("got %d goroutines with IDs, want none", len(p.RawFrames))
+	}
+	for _, want := range []struct {
+		key    string
+		count  int
+		stacks int
+		top    string
+		labels map[string]string
+	}{
+		{"chan receive_0", 5, 4, "runtime.gopark", nil},
+		{"semacquire_0", 3, 9, "runtime.gopark", map[string]string{"handler": "api"}},
+		{"running_0", 1, 7, "runtime.goroutineProfileWithLabels", nil},
+		{"sleep_0", 1, 2, "runtime.gopark", nil},
+	} {
+		tf, ok := p.TrimedFrames[want.key]
+		if !ok {
+			t.Errorf("no group %s", want.key)
+			continue
+		}
+		if tf.Count != want.count || len(tf.Stacks) != want.stacks {
+			t.Errorf("%s: got %d goroutines and %d frames, want %d and %d", want.key, tf.Count, len(tf.Stacks), want.count, want.stacks)
+			continue
+		}
+		if top := tf.Stacks[0].FuncName; top != want.top {
+			t.Errorf("%s: got innermost call %s, want %s", want.key, top, want.top)
+		}
+		if len(tf.Labels) != 0 || len(want.labels) != 0 {
+			if !reflect.DeepEqual(tf.Labels, want.labels) {
+				t.Errorf("%s: got labels %v, want %v", want.key, tf.Labels, want.labels)
+			}
+		}
+	}
+	if len(p.TrimedFrames) != 4 {
+		t.Errorf("got %d groups, want 4", len(p.TrimedFrames))
+	}
+}
+
+func TestIsProfile(t *testing.T) {
+	pb, err := ioutil.ReadAll(mustDecompress(t, "goroutine.pb.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isProfile(pb[:headerSize]) {
+		t.Errorf("profile not taken for one")
+	}
+	text, err := ioutil.ReadFile("testdata/go1.21.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Colored logs and stray NULs are still text.
+	for _, prefix := range []string{"", "\x1b[0m\n", "\x00\x00\n", "\n\x02\n"} {
+		data := append([]byte(prefix), text...)
+		if isProfile(data[:headerSize]) {
+			t.Errorf("text starting with %q taken for a profile", prefix)
+		}
+		p := NewDump()
+		if err := p.ParseData(string(data)); err != nil {
+			t.Errorf("text starting with %q: %v", prefix, err)
+		} else if len(p.RawFrames) != 4 {
+			t.Errorf("text starting with %q: got %d goroutines, want 4", prefix, len(p.RawFrames))
+		}
+	}
+}
+
+// mustDecompress opens the compressed file testdata/name.
+func mustDecompress(t *testing.T, name string) io.Reader {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Decompress(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
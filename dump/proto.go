@@ -6,89 +6,150 @@ import (
 	"strings"
 )
 
+var (
+	// goroutine 66926 [semacquire, 2031 minutes]:
+	// goroutine 1 gp=0xc000002380 m=0 mp=0x5c1a40 [chan receive, 5 minutes, locked to thread]:
+	headRE = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[(.*)\]:$`)
+)
+
+// decodeHead decodes the goroutine ID, wait reason, wait duration and
+// thread lock of a goroutine header.
 func (f *Frame) decodeHead(header string) {
-	// goroutine [0-9]* \[[\w ]*, [0-9]* minutes]
-	reg := regexp.MustCompile(`goroutine ([\d]+) \[([\w ._]+), ([\d]+) minutes]:`)
-	params := reg.FindStringSubmatch(header)
-	if len(params) == 4 {
-		head := Head{}
-		head.GID, _ = strconv.Atoi(params[1])
-		head.Duration, _ = strconv.Atoi(params[3])
-		f.Head = head
-		f.Reason = params[2]
+	params := headRE.FindStringSubmatch(header)
+	if len(params) != 3 {
 		return
 	}
-
-	// goroutine [0-9]* \[[\w ]*]
-	reg = regexp.MustCompile(`goroutine ([\d]+) \[([\w._]+)]:`)
-	params = reg.FindStringSubmatch(header)
-	if len(params) == 3 {
-		head := Head{}
-		head.GID, _ = strconv.Atoi(params[1])
-		f.Head = head
-		f.Reason = params[2]
-		return
+	head := Head{}
+	head.GID, _ = strconv.Atoi(params[1])
+	fields := strings.Split(params[2], ", ")
+	for _, field := range fields[1:] {
+		switch {
+		case field == "locked to thread":
+			f.LockedToThread = true
+		case strings.HasSuffix(field, " minutes"):
+			head.Duration, _ = strconv.Atoi(strings.TrimSuffix(field, " minutes"))
+		}
 	}
-	return
+	f.Head = head
+	f.Reason = fields[0]
 }
 
+// decodeBody decodes the stack of a goroutine. Each call is printed
+// on one line and followed by an indented line with its location, but
+// the runtime may also print markers for elided frames, the function
+// that created the goroutine, and diagnostics, so lines are classified
+// one by one instead of being read in pairs.
 func (f *Frame) decodeBody(body []string) {
-	for i := 0; i < len(body)-1; i += 2 {
-		stack := Stack{}
-		reg := regexp.MustCompile(`([\D\w.\-\/\(\*\)]+)\(([0x\w, ]+)\)`)
-		funcAndParams := reg.FindStringSubmatch(body[i])
-		if len(funcAndParams) == 3 {
-			stack.FuncName = funcAndParams[1]
-			stack.Params = funcAndParams[2]
-		} else {
-			stack.FuncName = body[i]
-		}
-		if len(body[i+1]) > 0 {
-			strLoc := body[i+1][1:]
-			stack.Location = strLoc
-			reg = regexp.MustCompile(`([\w.\-\/:\d]+) ([+0x\d]+)`)
-			locations := reg.FindStringSubmatch(strLoc)
-			if len(locations) > 0 {
-				stack.Location = locations[1]
+	var location *string // location the next indented line belongs to
+	var pcOffset *uint64 // PC offset of the call, if location is of a call
+	for _, line := range body {
+		switch {
+		case line == "":
+		case line[0] == '\t' || line[0] == ' ':
+			if location != nil {
+				loc, offset := decodeLocation(line)
+				*location = loc
+				if pcOffset != nil {
+					*pcOffset = offset
+				}
+				location, pcOffset = nil, nil
 			}
+			continue
+		case strings.HasPrefix(line, "...") && strings.HasSuffix(line, " elided..."):
+			f.Elided = decodeElided(line)
+		case strings.HasPrefix(line, "created by "):
+			f.Creator = decodeCreator(line)
+			location, pcOffset = &f.Creator.Location, nil
+			continue
+		default:
+			f.Stacks = append(f.Stacks, decodeCall(line))
+			stack := &f.Stacks[len(f.Stacks)-1]
+			location, pcOffset = &stack.Location, &stack.PCOffset
+			continue
 		}
-		f.Stacks = append(f.Stacks, stack)
+		location, pcOffset = nil, nil
+	}
+	for i := range f.Stacks {
+		f.Stacks[i].decode()
 	}
 	f.Size = len(body)
-	f.checkHoldLock()
+}
+
+// decodeCreator decodes a "created by main.main in goroutine 1" line.
+func decodeCreator(line string) *Creator {
+	c := &Creator{FuncName: strings.TrimPrefix(line, "created by ")}
+	if i := strings.Index(c.FuncName, " in goroutine "); i >= 0 {
+		c.GID, _ = strconv.Atoi(c.FuncName[i+len(" in goroutine "):])
+		c.FuncName = c.FuncName[:i]
+	}
+	return c
+}
+
+// decodeCall decodes a call line such as
+// "main.(*T).f({0xc000010000, 0x1}, 0x2?, ...)". The arguments never
+// contain parentheses, so they start after the last one.
+func decodeCall(line string) Stack {
+	stack := Stack{FuncName: line}
+	if !strings.HasSuffix(line, ")") {
+		return stack
+	}
+	if i := strings.LastIndexByte(line, '('); i > 0 {
+		stack.FuncName = line[:i]
+		stack.Params = line[i+1 : len(line)-1]
+	}
+	return stack
+}
 
-	return
+// decodeLocation returns the file:line and the PC offset of a location
+// line such as
+// "\t/usr/local/go/src/sync/mutex.go:138 +0x105 fp=0xc00005cf40".
+func decodeLocation(line string) (loc string, pcOffset uint64) {
+	loc = strings.TrimSpace(line)
+	if i := strings.Index(loc, " fp="); i >= 0 {
+		loc = loc[:i]
+	}
+	if i := strings.Index(loc, " +0x"); i >= 0 {
+		pcOffset, _ = strconv.ParseUint(loc[i+len(" +0x"):], 16, 64)
+		loc = loc[:i]
+	}
+	return loc, pcOffset
 }
 
-func (f *Frame) checkHoldLock() {
-	if len(f.Stacks) < 2 {
+// decodeElided returns the number of frames elided by the runtime, or
+// -1 for "...additional frames elided..." which does not print it.
+func decodeElided(line string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "..."), " frames elided..."))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// checkHoldLock records the lock f is blocked acquiring, as recognised
+// by patterns, with the call acquiring it, and the receiver types of the
+// calls further out, which may hold other locks.
+func (f *Frame) checkHoldLock(patterns []LockPattern) {
+	f.LockInfo = LockInfo{}
+	w := f.decodeLockWait(patterns)
+	if w == nil || w.Stack+1 >= len(f.Stacks) {
 		return
 	}
-	for i := len(f.Stacks) - 2; i >= 0; i-- {
-		funcName := f.Stacks[i].FuncName
-		tfunc := strings.ToLower(funcName)
-		if strings.Contains(tfunc, ".lock") {
-			f.LockInfo.Stack = &f.Stacks[i+1]
-			f.LockType = funcName
-			reg := regexp.MustCompile(`\(([\w.*]+)\)`)
-			for j := i + 1; j < len(f.Stacks); j++ {
-				holdFunc := f.Stacks[j].FuncName
-				holders := reg.FindStringSubmatch(holdFunc)
-				if len(holders) > 1 {
-					tHolder := holders[1]
-					find := false
-					for _, holder := range f.LockHolders {
-						if holder == tHolder {
-							find = true
-							break
-						}
-					}
-					if !find {
-						f.LockHolders = append(f.LockHolders, tHolder)
-					}
-				}
+	f.LockInfo.Stack = &f.Stacks[w.Stack+1]
+	f.LockType = w.Kind
+	for _, s := range f.Stacks[w.Stack+1:] {
+		if !s.PointerReceiver {
+			continue
+		}
+		holder := "*" + s.Receiver
+		find := false
+		for _, h := range f.LockHolders {
+			if h == holder {
+				find = true
+				break
 			}
-			return
+		}
+		if !find {
+			f.LockHolders = append(f.LockHolders, holder)
 		}
 	}
 }
@@ -97,6 +158,18 @@ func (f *Frame) hasHighSimilarity(f2 *Frame) (ret bool) {
 	if len(f.Stacks) != len(f2.Stacks) {
 		return false
 	}
+	if (f.Creator == nil) != (f2.Creator == nil) ||
+		f.Creator != nil && f.Creator.FuncName != f2.Creator.FuncName {
+		return false
+	}
+	if len(f.Labels) != len(f2.Labels) {
+		return false
+	}
+	for k, v := range f.Labels {
+		if f2.Labels[k] != v {
+			return false
+		}
+	}
 	for i := 0; i < len(f.Stacks); i++ {
 		if f.Stacks[i].FuncName == f2.Stacks[i].FuncName &&
 			f.Stacks[i].Location == f2.Stacks[i].Location {
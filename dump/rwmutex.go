@@ -0,0 +1,131 @@
+package dump
+
+import "sort"
+
+// An RWMutex is locked by one writer or by several readers. A writer
+// first acquires the Mutex at the start of the RWMutex, which holds off
+// other writers, then waits for the readers to unlock, which holds off
+// new readers. So goroutines blocked on an RWMutex wait for one of
+//
+//	sync.(*Mutex).Lock    writer waiting for another writer (WaitUnlock)
+//	sync.(*RWMutex).Lock  writer waiting for the readers (WaitReaders)
+//	sync.(*RWMutex).RLock reader waiting for a writer (WaitWriter)
+
+// RWMutexWaits is the goroutines waiting for an RWMutex.
+type RWMutexWaits struct {
+	Addr    uint64
+	Writers []*LockWait // writers waiting for another writer
+	Pending []*LockWait // writers waiting for the readers
+	Readers []*LockWait // readers waiting for a writer
+	Holders []LockHold  // probable holders of a read or write lock
+}
+
+// RWMutexes returns the RWMutexes goroutines wait for in g, the wait
+// graph of p, ordered by address, with the goroutines waiting in each
+// state.
+func (p *Dump) RWMutexes(g *WaitGraph) []*RWMutexWaits {
+	var gids []int
+	for gid := range g.Waits {
+		gids = append(gids, gid)
+	}
+	sort.Ints(gids)
+	locks := make(map[uint64]*RWMutexWaits)
+	for _, gid := range gids {
+		w := g.Waits[gid]
+		if w.Kind != RWMutexLock && w.Kind != RWMutexRLock {
+			continue
+		}
+		m := locks[w.Addr]
+		if m == nil {
+			m = &RWMutexWaits{Addr: w.Addr, Holders: g.Holders[w.Addr]}
+			locks[w.Addr] = m
+		}
+		switch w.State {
+		case WaitUnlock:
+			m.Writers = append(m.Writers, w)
+		case WaitReaders:
+			m.Pending = append(m.Pending, w)
+		case WaitWriter:
+			m.Readers = append(m.Readers, w)
+		}
+	}
+	rws := make([]*RWMutexWaits, 0, len(locks))
+	for _, m := range locks {
+		rws = append(rws, m)
+	}
+	sort.Slice(rws, func(i, j int) bool { return rws[i].Addr < rws[j].Addr })
+	return rws
+}
+
+// StarvedWriter is a writer waiting for the readers of an RWMutex for at
+// least a minute, as readers keep locking it, while new readers and
+// writers queue behind it.
+type StarvedWriter struct {
+	*LockWait
+	Lock *RWMutexWaits
+}
+
+// StarvedWriters returns the writers starved by the readers of an
+// RWMutex in g, the wait graph of p. The runtime prints wait durations of a minute or more only,
+// so writers waiting for less are not told from ones about to lock.
+func (p *Dump) StarvedWriters(g *WaitGraph) []StarvedWriter {
+	var starved []StarvedWriter
+	for _, m := range p.RWMutexes(g) {
+		for _, w := range m.Pending {
+			if f := p.GetFrameByGID(w.GID); f != nil && f.Duration > 0 {
+				starved = append(starved, StarvedWriter{LockWait: w, Lock: m})
+			}
+		}
+	}
+	return starved
+}
+
+// RecursiveRLock is a reader waiting for an RWMutex it probably read
+// locked already, as a call further out was passed the RWMutex. A
+// pending writer waits for the first read lock to be unlocked and the
+// reader waits for the writer, so they deadlock.
+type RecursiveRLock struct {
+	*LockWait
+	Outer  int       // index of the call further out passed the RWMutex
+	Writer *LockWait // writer the reader waits for, nil if not found
+}
+
+// RecursiveRLocks returns the readers blocked on an RWMutex they
+// probably hold, in g, the wait graph of p.
+func (p *Dump) RecursiveRLocks(g *WaitGraph) []RecursiveRLock {
+	var recursive []RecursiveRLock
+	for _, m := range p.RWMutexes(g) {
+		for _, w := range m.Readers {
+			outer := p.outerCall(w)
+			if outer < 0 {
+				continue
+			}
+			r := RecursiveRLock{LockWait: w, Outer: outer}
+			if len(m.Pending) > 0 {
+				r.Writer = m.Pending[0]
+			} else if len(m.Writers) > 0 {
+				r.Writer = m.Writers[0]
+			}
+			recursive = append(recursive, r)
+		}
+	}
+	return recursive
+}
+
+// outerCall returns the index of the outermost call of the goroutine of
+// w, past the call acquiring the lock, that was passed the lock, or -1.
+// Both calls must have been passed the address of the lock itself, not
+// of its owner or in a word that may be stale, as an object may own
+// several locks and a stale word may be anything.
+func (p *Dump) outerCall(w *LockWait) int {
+	outer := -1
+	if w.ByOwner {
+		return outer
+	}
+	for _, o := range p.Addresses[w.Addr] {
+		if o.GID == w.GID && !o.Stale && o.Stack > w.Stack+1 && o.Stack > outer {
+			outer = o.Stack
+		}
+	}
+	return outer
+}
@@ -0,0 +1,79 @@
+package dump
+
+import "testing"
+
+func TestRecursiveRLocks(t *testing.T) {
+	// Goroutine 5 read locks the RWMutex of a Store in Get, then again in
+	// get, behind goroutine 6 waiting to write lock it.
+	const recursive = `goroutine 5 [sync.RWMutex.RLock]:
+runtime.semacquire1(0xc0000b600c, 0x0, 0x0, 0x0, 0x0)
+	/usr/local/go/src/runtime/sema.go:160 +0x232
+sync.runtime_SemacquireRWMutexR(0xc0000b600c?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:82 +0x25
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:71
+main.(*Store).get(0xc0000b6000, {0x481001, 0x1})
+	/src/app/main.go:23 +0x58
+main.(*Store).Get(0xc0000b6000, {0x481001, 0x1})
+	/src/app/main.go:18 +0x99
+
+goroutine 6 [sync.RWMutex.Lock]:
+runtime.semacquire1(0xc0000b6008, 0x0, 0x0, 0x0, 0x0)
+	/usr/local/go/src/runtime/sema.go:160 +0x232
+sync.runtime_SemacquireRWMutex(0xc0000b6008?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:87 +0x25
+sync.(*RWMutex).Lock(0xc0000b6000)
+	/usr/local/go/src/sync/rwmutex.go:152 +0x71
+main.(*Store).Set(0xc0000b6000, {0x481001, 0x1}, 0x1)
+	/src/app/main.go:30 +0x45
+`
+	for _, tc := range []struct {
+		name string
+		dump string
+		want int
+	}{
+		{"lock address", recursive, 1},
+		// Without the semaphore, the lock is only told by its owner,
+		// which may own other locks.
+		{"owner address", `goroutine 5 [sync.RWMutex.RLock]:
+sync.runtime_SemacquireRWMutexR(0xc0000b600c?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:82 +0x25
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:71
+main.(*Store).get(0xc0000b6000, {0x481001, 0x1})
+	/src/app/main.go:23 +0x58
+main.(*Store).Get(0xc0000b6000, {0x481001, 0x1})
+	/src/app/main.go:18 +0x99
+`, 0},
+		// The outer call may have been passed anything.
+		{"stale address", `goroutine 5 [sync.RWMutex.RLock]:
+runtime.semacquire1(0xc0000b600c, 0x0, 0x0, 0x0, 0x0)
+	/usr/local/go/src/runtime/sema.go:160 +0x232
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:71
+main.(*Store).get(0xc0000b6000?, {0x481001?, 0x1?})
+	/src/app/main.go:23 +0x58
+main.(*Store).Get(0xc0000b6000?, {0x481001?, 0x1?})
+	/src/app/main.go:18 +0x99
+`, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewDump()
+			if err := p.ParseData(tc.dump); err != nil {
+				t.Fatal(err)
+			}
+			got := p.RecursiveRLocks(p.WaitGraph())
+			if len(got) != tc.want {
+				t.Fatalf("got %d recursive read locks, want %d", len(got), tc.want)
+			}
+			for _, r := range got {
+				if r.GID != 5 || r.Addr != 0xc0000b6000 || r.Outer != 4 {
+					t.Errorf("got goroutine %d waiting for %#x, read locked in call %d", r.GID, r.Addr, r.Outer)
+				}
+				if r.Writer == nil || r.Writer.GID != 6 {
+					t.Errorf("got writer %+v, want goroutine 6", r.Writer)
+				}
+			}
+		})
+	}
+}
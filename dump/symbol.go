@@ -0,0 +1,148 @@
+package dump
+
+import (
+	"strconv"
+	"strings"
+)
+
+// decode decodes the function name, location and arguments of a call
+// into the typed fields of s. A function name such as
+//
+//	github.com/docker/docker/vendor/gopkg.in/yaml%2ev2.(*parser[...]).parse.func1
+//
+// is made of the import path of its package, in which the dots of the
+// last element are escaped, then of the receiver type of a method, the
+// name of the function or method, and the suffix of a closure.
+func (s *Stack) decode() {
+	s.Args, s.ArgsElided = decodeArgs(s.Params)
+
+	s.File, s.Line = s.Location, 0
+	if i := strings.LastIndexByte(s.Location, ':'); i > 0 {
+		if line, err := strconv.Atoi(s.Location[i+1:]); err == nil {
+			s.File, s.Line = s.Location[:i], line
+		}
+	}
+
+	name := s.FuncName
+	// Type arguments are printed as [...], by a receiver type or by a
+	// function, and may themselves contain dots and slashes.
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		if j := closingBracket(name, i); j > i {
+			s.TypeArgs = name[i : j+1]
+			name = name[:i] + name[j+1:]
+		}
+	}
+
+	slash := strings.LastIndexByte(name, '/')
+	dot := strings.IndexByte(name[slash+1:], '.')
+	if dot < 0 {
+		s.Method = name
+		return
+	}
+	dot += slash + 1
+	s.ImportPath = importPath(name[:dot])
+	s.Package = packageName(s.ImportPath)
+
+	rest := name[dot+1:]
+	if strings.HasPrefix(rest, "(") {
+		if i := strings.IndexByte(rest, ')'); i > 0 {
+			s.Receiver = rest[1:i]
+			if strings.HasPrefix(s.Receiver, "*") {
+				s.Receiver = s.Receiver[1:]
+				s.PointerReceiver = true
+			}
+			rest = strings.TrimPrefix(rest[i+1:], ".")
+		}
+	}
+	// Method values are wrapped in a function suffixed with -fm.
+	if strings.HasSuffix(rest, "-fm") {
+		s.Closure = "-fm"
+		rest = strings.TrimSuffix(rest, "-fm")
+	}
+	parts := strings.Split(rest, ".")
+	for i := 1; i < len(parts); i++ {
+		if isClosure(parts[i]) {
+			s.Closure = "." + strings.Join(parts[i:], ".") + s.Closure
+			parts = parts[:i]
+			break
+		}
+	}
+	// Methods with a value receiver are printed as Type.Method.
+	if s.Receiver == "" && len(parts) == 2 {
+		s.Receiver = parts[0]
+		parts = parts[1:]
+	}
+	s.Method = strings.Join(parts, ".")
+}
+
+// closingBracket returns the index of the bracket closing the one at
+// index i of s, or -1.
+func closingBracket(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// importPath returns the import path of a package as printed in a
+// function name, unescaping the dots of its last element and removing
+// the prefix of vendored packages.
+func importPath(path string) string {
+	path = strings.Replace(path, "%2e", ".", -1)
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		return path[i+len("/vendor/"):]
+	}
+	return strings.TrimPrefix(path, "vendor/")
+}
+
+// packageName guesses the name of a package from its import path, which
+// may end with a major version, as in gopkg.in/yaml.v2 or
+// github.com/go-redis/redis/v8.
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func isMajorVersion(s string) bool {
+	return len(s) > 1 && s[0] == 'v' && isDigits(s[1:])
+}
+
+// isClosure reports whether part of a function name is the suffix of a
+// closure, as in main.main.func1.2, main.main.gowrap1, or
+// pkg.glob..func1 for closures of package variables.
+func isClosure(part string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap", ""} {
+		if strings.HasPrefix(part, prefix) && isDigits(part[len(prefix):]) {
+			return true
+		}
+	}
+	return part == ""
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
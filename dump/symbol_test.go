@@ -0,0 +1,42 @@
+package dump
+
+import "testing"
+
+func TestStackDecode(t *testing.T) {
+	for _, tc := range []struct {
+		funcName string
+		want     Stack
+	}{
+		{"runtime.gopark", Stack{ImportPath: "runtime", Package: "runtime", Method: "gopark"}},
+		{"sync.(*Mutex).Lock", Stack{ImportPath: "sync", Package: "sync", Receiver: "Mutex", PointerReceiver: true, Method: "Lock"}},
+		{"time.Duration.String", Stack{ImportPath: "time", Package: "time", Receiver: "Duration", Method: "String"}},
+		// Method values.
+		{"main.(*Store).Get-fm", Stack{ImportPath: "main", Package: "main", Receiver: "Store", PointerReceiver: true, Method: "Get", Closure: "-fm"}},
+		{"net/http.HandlerFunc.ServeHTTP-fm", Stack{ImportPath: "net/http", Package: "http", Receiver: "HandlerFunc", Method: "ServeHTTP", Closure: "-fm"}},
+		// Generics.
+		{"example.com/app/cache.(*LRU[...]).Get", Stack{ImportPath: "example.com/app/cache", Package: "cache", Receiver: "LRU", PointerReceiver: true, Method: "Get", TypeArgs: "[...]"}},
+		{"example.com/app.Map[go.shape.int,go.shape.string]", Stack{ImportPath: "example.com/app", Package: "app", Method: "Map", TypeArgs: "[go.shape.int,go.shape.string]"}},
+		{"example.com/app.(*Set[...]).Add.func1", Stack{ImportPath: "example.com/app", Package: "app", Receiver: "Set", PointerReceiver: true, Method: "Add", Closure: ".func1", TypeArgs: "[...]"}},
+		// Closures.
+		{"main.main.func1.2", Stack{ImportPath: "main", Package: "main", Method: "main", Closure: ".func1.2"}},
+		{"main.main.gowrap1", Stack{ImportPath: "main", Package: "main", Method: "main", Closure: ".gowrap1"}},
+		{"example.com/app.glob..func1", Stack{ImportPath: "example.com/app", Package: "app", Method: "glob", Closure: "..func1"}},
+		// Escaped, versioned and vendored import paths.
+		{"gopkg.in/yaml%2ev2.(*parser).parse", Stack{ImportPath: "gopkg.in/yaml.v2", Package: "yaml", Receiver: "parser", PointerReceiver: true, Method: "parse"}},
+		{"github.com/go-redis/redis/v8.(*Client).Get", Stack{ImportPath: "github.com/go-redis/redis/v8", Package: "redis", Receiver: "Client", PointerReceiver: true, Method: "Get"}},
+		{"github.com/docker/docker/vendor/golang.org/x/net/http2.(*Framer).ReadFrame", Stack{ImportPath: "golang.org/x/net/http2", Package: "http2", Receiver: "Framer", PointerReceiver: true, Method: "ReadFrame"}},
+		// Package initialisation, numbered when a file has several.
+		{"example.com/app.init", Stack{ImportPath: "example.com/app", Package: "app", Method: "init"}},
+		{"example.com/app.init.0", Stack{ImportPath: "example.com/app", Package: "app", Method: "init", Closure: ".0"}},
+	} {
+		s := Stack{FuncName: tc.funcName, Location: "/src/app/main.go:12"}
+		s.decode()
+		tc.want.FuncName, tc.want.Location = s.FuncName, s.Location
+		tc.want.File, tc.want.Line = "/src/app/main.go", 12
+		if s.ImportPath != tc.want.ImportPath || s.Package != tc.want.Package || s.Receiver != tc.want.Receiver ||
+			s.PointerReceiver != tc.want.PointerReceiver || s.Method != tc.want.Method || s.Closure != tc.want.Closure ||
+			s.TypeArgs != tc.want.TypeArgs || s.File != tc.want.File || s.Line != tc.want.Line {
+			t.Errorf("%s: got %+v, want %+v", tc.funcName, s, tc.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package dump
+
+import "sort"
+
+// Trend is the evolution of the goroutine groups over snapshots of a
+// process taken over time.
+type Trend struct {
+	Times  []float64     // minutes since the first snapshot, or snapshot indexes if not Timed
+	Timed  bool          // whether every snapshot was timed, in order
+	Groups []*GroupTrend // leak suspects first, the fastest growing first
+}
+
+// GroupTrend is the evolution of the goroutines sharing a stack
+// signature.
+type GroupTrend struct {
+	Signature string
+	Frame     *TrimedFrame // group in the latest snapshot it is in
+	Counts    []int        // goroutines in each snapshot
+	Minutes   []int        // longest wait in each snapshot, -1 where the group is absent
+	Slope     float64      // goroutines gained per unit of Times, fitted by least squares
+}
+
+// NewTrend tracks the goroutine groups of series, ordered as the
+// snapshots were taken, by stack signature.
+func NewTrend(series []*Dump) *Trend {
+	t := &Trend{Times: snapshotTimes(series)}
+	t.Timed = t.Times != nil
+	if !t.Timed {
+		t.Times = make([]float64, len(series))
+		for i := range series {
+			t.Times[i] = float64(i)
+		}
+	}
+
+	groups := make(map[string]*GroupTrend)
+	for i, p := range series {
+		for _, key := range sortedKeys(p.TrimedFrames) {
+			tf := p.TrimedFrames[key]
+			sig := tf.Signature()
+			g := groups[sig]
+			if g == nil {
+				g = &GroupTrend{Signature: sig, Counts: make([]int, len(series)), Minutes: make([]int, len(series))}
+				for j := range g.Minutes {
+					g.Minutes[j] = -1
+				}
+				groups[sig] = g
+			}
+			g.Frame = &tf
+			g.Counts[i] += tf.Count
+			if m := tf.Minutes(); m > g.Minutes[i] {
+				g.Minutes[i] = m
+			}
+		}
+	}
+
+	for _, g := range groups {
+		g.Slope = slope(t.Times, g.Counts)
+		t.Groups = append(t.Groups, g)
+	}
+	rank := func(g *GroupTrend) int {
+		switch {
+		case g.Growing():
+			return 0
+		case g.Aging():
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(t.Groups, func(i, j int) bool {
+		gi, gj := t.Groups[i], t.Groups[j]
+		if ri, rj := rank(gi), rank(gj); ri != rj {
+			return ri < rj
+		}
+		if gi.Slope != gj.Slope {
+			return gi.Slope > gj.Slope
+		}
+		return gi.Signature < gj.Signature
+	})
+	return t
+}
+
+// snapshotTimes returns the minutes since the first snapshot of each
+// snapshot, or nil unless every snapshot was timed, in order.
+func snapshotTimes(series []*Dump) []float64 {
+	times := make([]float64, len(series))
+	for i, p := range series {
+		if p.Source.Time.IsZero() || i > 0 && !p.Source.Time.After(series[i-1].Source.Time) {
+			return nil
+		}
+		times[i] = p.Source.Time.Sub(series[0].Source.Time).Minutes()
+	}
+	return times
+}
+
+// slope fits counts to a line over times by least squares and returns
+// its slope.
+func slope(times []float64, counts []int) float64 {
+	n := float64(len(times))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxx, sxy float64
+	for i, x := range times {
+		y := float64(counts[i])
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / d
+}
+
+// Growing reports whether the goroutines of the group never decreased
+// over the snapshots, and increased overall: goroutines are started but
+// never end.
+func (g *GroupTrend) Growing() bool {
+	return grows(g.Counts)
+}
+
+// Aging reports whether the group is in every snapshot and its longest
+// wait never decreased, and increased overall: its oldest goroutine
+// never ends its wait.
+func (g *GroupTrend) Aging() bool {
+	for _, m := range g.Minutes {
+		if m < 0 {
+			return false
+		}
+	}
+	return grows(g.Minutes)
+}
+
+// LeakSuspect reports whether the group grows or ages.
+func (g *GroupTrend) LeakSuspect() bool {
+	return g.Growing() || g.Aging()
+}
+
+// grows reports whether values never decrease and the last is
+// greater than the first.
+func grows(values []int) bool {
+	if len(values) < 2 {
+		return false
+	}
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			return false
+		}
+	}
+	return values[len(values)-1] > values[0]
+}
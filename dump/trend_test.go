@@ -0,0 +1,88 @@
+package dump
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snapshot returns a dump taken at time at, with a goroutine waiting in
+// each function of groups for each of its waits in minutes.
+func snapshot(t *testing.T, at time.Time, groups map[string][]int) *Dump {
+	t.Helper()
+	var b strings.Builder
+	gid := 10
+	for fn, minutes := range groups {
+		for _, m := range minutes {
+			gid++
+			fmt.Fprintf(&b, "goroutine %d [chan receive, %d minutes]:\nmain.%s()\n\t/src/app/main.go:12 +0x45\n\n", gid, m, fn)
+		}
+	}
+	p := NewDump()
+	if err := p.ParseData(b.String()); err != nil {
+		t.Fatal(err)
+	}
+	p.Source.Time = at
+	return p
+}
+
+func TestNewTrend(t *testing.T) {
+	start := time.Date(2023, 9, 14, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	// Snapshots taken 1, 4 and 1 minutes apart. The workers grow by one
+	// a minute, the pollers appear in the second snapshot and keep
+	// waiting, and the handlers end.
+	series := []*Dump{
+		snapshot(t, at(0), map[string][]int{"worker": {1}, "handle": {1, 1, 1}}),
+		snapshot(t, at(1), map[string][]int{"worker": {2, 1}, "poll": {2}, "handle": {2, 1}}),
+		snapshot(t, at(5), map[string][]int{"worker": {6, 5, 4, 3, 2, 1}, "poll": {6}, "handle": {1}}),
+		snapshot(t, at(6), map[string][]int{"worker": {7, 6, 5, 4, 3, 2, 1}, "poll": {7}, "handle": {1}}),
+	}
+	trend := NewTrend(series)
+	if !trend.Timed || !reflect.DeepEqual(trend.Times, []float64{0, 1, 5, 6}) {
+		t.Fatalf("got times %v, timed %v, want [0 1 5 6] minutes", trend.Times, trend.Timed)
+	}
+
+	want := []struct {
+		fn              string
+		counts, minutes []int
+		slope           float64
+		growing, aging  bool
+	}{
+		{"main.worker", []int{1, 2, 6, 7}, []int{1, 2, 6, 7}, 1, true, true},
+		{"main.poll", []int{0, 1, 1, 1}, []int{-1, 2, 6, 7}, 12.0 / 104, true, false},
+		{"main.handle", []int{3, 2, 1, 1}, []int{1, 2, 1, 1}, -32.0 / 104, false, false},
+	}
+	if len(trend.Groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(trend.Groups), len(want))
+	}
+	for i, w := range want {
+		g := trend.Groups[i]
+		if fn := g.Frame.Stacks[0].FuncName; fn != w.fn {
+			t.Errorf("group %d: got %s, want %s", i, fn, w.fn)
+			continue
+		}
+		if !reflect.DeepEqual(g.Counts, w.counts) || !reflect.DeepEqual(g.Minutes, w.minutes) {
+			t.Errorf("%s: got goroutines %v waiting %v, want %v waiting %v", w.fn, g.Counts, g.Minutes, w.counts, w.minutes)
+		}
+		if math.Abs(g.Slope-w.slope) > 1e-9 {
+			t.Errorf("%s: got slope %v, want %v", w.fn, g.Slope, w.slope)
+		}
+		if g.Growing() != w.growing || g.Aging() != w.aging {
+			t.Errorf("%s: got growing %v, aging %v, want %v, %v", w.fn, g.Growing(), g.Aging(), w.growing, w.aging)
+		}
+	}
+
+	// Without the time of a snapshot, the slope is per snapshot.
+	series[2].Source.Time = time.Time{}
+	trend = NewTrend(series)
+	if trend.Timed || !reflect.DeepEqual(trend.Times, []float64{0, 1, 2, 3}) {
+		t.Fatalf("got times %v, timed %v, want snapshot indexes", trend.Times, trend.Timed)
+	}
+	if g := trend.Groups[0]; g.Slope != 2.2 {
+		t.Errorf("got slope %v, want 2.2 goroutines a snapshot", g.Slope)
+	}
+}
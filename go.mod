@@ -3,6 +3,7 @@ module github.com/shippomx/grains
 go 1.16
 
 require (
-	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
+	github.com/klauspost/compress v1.13.6
 	golang.org/x/sys v0.0.0-20211205182925-97ca703d548d // indirect
 )
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/shippomx/grains/dump"
 	"github.com/shippomx/grains/internal/plugin"
 )
 
@@ -12,6 +13,7 @@ type source struct {
 	ExecName  string
 	Base      []string
 	Normalize bool
+	Options   dump.Options // options the dumps are parsed with
 }
 
 // parseFlags parses the command lines through the specified flags package
@@ -20,7 +22,7 @@ type source struct {
 func parseFlags(o *plugin.Options) (*source, []string, error) {
 	flag := o.Flagset
 	// Comparisons.
-	flagBase := flag.StringList("base", "", "Source of base dump for dump subtraction")
+	flagBase := flag.StringList("base", "", "Source of base dump to compare with")
 
 	cfg := currentConfig()
 	configFlagSetter := installConfigFlags(flag, &cfg)
@@ -30,6 +32,8 @@ func parseFlags(o *plugin.Options) (*source, []string, error) {
 	for name, cmd := range grainsCommands {
 		if cmd.hasParam {
 			flagParamCommands[name] = flag.String(name, "", "Generate a report in "+name+" format, matching regexp")
+		} else {
+			flagCommands[name] = flag.Bool(name, false, "Generate a report in "+name+" format")
 		}
 	}
 
@@ -184,9 +188,11 @@ Details:
 `
 var usageMsgSrc = "\n\n" +
 	"  Source options:\n" +
-	"    -base source       Source of base dump for dump subtraction\n" +
-	"    dockerd.tar.gz		Dump in compressed protobuf format\n" +
-	"    dockerd.dlog		Dump in string format\n"
+	"    -base source       Source of base dump to compare with, by diff\n" +
+	"    dockerd.pb.gz		Dump in compressed protobuf format, as saved by -proto\n" +
+	"    dockerd.dlog		Dump in string format\n" +
+	"    dumps.tar.gz		Archive holding a dump per file\n" +
+	"                       Sources may be compressed with gzip, bzip2 or zstd\n"
 
 var usageMsgVars = "\n\n" +
 	"  Environment Variables:\n" +
@@ -47,6 +47,21 @@ var grainsCommands = commands{
 	"trim": {report.Text, nil, nil, false, "Trim the dump", reportHelp("trim", true, true)},
 	"show": {report.Text, nil, nil, true, "show the goroutine", reportHelp("show", true, true)},
 	"dump": {report.Text, nil, nil, false, "dump stacks to file", reportHelp("dump", true, true)},
+	"tree": {report.Text, nil, nil, true, "Print the goroutine spawn tree", treeHelp},
+
+	"snapshots": {report.Text, nil, nil, false, "List the snapshots read", snapshotsHelp},
+	"crash":     {report.Text, nil, nil, false, "Summarise the crash that printed the dump", crashHelp},
+	"who":       {report.Text, nil, nil, true, "List the goroutines passed an object", whoHelp},
+	"holders":   {report.Text, nil, nil, false, "Rank the probable holders of contended locks", holdersHelp},
+	"channels":  {report.Text, nil, nil, false, "Group the goroutines blocked by channel and report leaks", channelsHelp},
+	"waits":     {report.Text, nil, nil, false, "Group the goroutines waiting on WaitGroups, Conds, semaphores and errgroups", waitsHelp},
+	"findings":  {report.Text, nil, nil, false, "Rank the crash, deadlocks, leak suspects and contention hotspots found", findingsHelp},
+	"diff":      {report.Text, nil, nil, false, "Compare the goroutine groups with those of the base dump", diffHelp},
+	"trend":     {report.Text, nil, nil, false, "Track the goroutine groups over the snapshots and report leak suspects", trendHelp},
+	"cluster":   {report.Text, nil, nil, false, "Cluster the goroutine groups with similar stacks", clusterHelp},
+
+	// Save binary formats to a file
+	"proto": {report.Proto, nil, nil, false, "Outputs the dump in compressed protobuf format", "proto >f\nSave the dump on the file f, which grains can read back."},
 }
 
 // configHelp contains help text per configuration parameter.
@@ -56,8 +71,150 @@ var configHelp = map[string]string{
 	"trim": helpText(
 		"trim dump file more readable",
 		""),
+
+	// Filtering options
+	"depth": helpText(
+		"Maximum depth of the spawn tree",
+		"Use 0 for an unlimited depth."),
+	"snapshot": helpText(
+		"Snapshot to report on, as numbered by snapshots",
+		"Use 0 for the latest snapshot."),
+	"minutes": helpText(
+		"Minutes goroutines are blocked for to be reported as leaked",
+		"The runtime prints the time a goroutine is blocked for from a minute on,",
+		"and busy goroutines often block for a few minutes, so the default is 10."),
+
+	// Grouping options
+	"group": helpText(
+		"How trim and dump group goroutines",
+		"One of exact, ignore_lines, functions, top, bottom or package.",
+		"Goroutines are grouped by reason and labels too."),
+	"exact":        helpText("Group goroutines by their functions and locations"),
+	"ignore_lines": helpText("Group goroutines by their functions and files, ignoring line numbers"),
+	"functions":    helpText("Group goroutines by their functions, counting recursive calls once"),
+	"top":          helpText("Group goroutines by their group_frames innermost functions past the runtime, whatever their creator"),
+	"bottom":       helpText("Group goroutines by their group_frames outermost functions"),
+	"package":      helpText("Group goroutines by the packages of their calls, whatever their creator"),
+	"group_frames": helpText("Number of frames compared by top and bottom grouping"),
+	"similarity": helpText(
+		"Similarity of the stacks of the groups cluster merges, from 0 to 1",
+		"Stacks are compared by the edit distance between their functions,",
+		"over the number of functions of the longer one."),
+
+	// Analysis options
+	"locks": helpText(
+		"JSON file of lock patterns, in addition to the built-in ones",
+		"Each pattern has a func regexp matching the name of a function",
+		"acquiring a lock, the kind of lock, and the index of the arg word",
+		"holding its address, or sets release for a function releasing it:",
+		`[{"func": "example\\.com/kvlock\\.\\(\\*Lock\\)\\.Acquire", "kind": "kvlock.Lock", "arg": 0}]`),
+	"merge": helpText(
+		"Merge the dumps of all sources into a fleet-wide one",
+		"Each source is taken for an instance of the program, such as a",
+		"replica of a service, and its latest snapshot is merged. Groups",
+		"count the goroutines of each instance, and show looks goroutines",
+		"up as gid@instance, the instance being its number or source. Locks,",
+		"channels, waits and findings are analysed per instance."),
 }
 
+var treeHelp = strings.Join([]string{
+	"tree gid|all >f",
+	"Print the goroutines started by each go statement as a tree, following",
+	"the parent goroutine recorded by Go 1.21 and later. Goroutines started",
+	"by the same go statement are collapsed into one node with their count.",
+	"Root the tree at goroutine gid, or at every goroutine without a parent",
+	"for all, on the instance of a merged dump selected as gid@instance.",
+	"Optionally save the report on the file f.",
+}, "\n")
+
+var crashHelp = strings.Join([]string{
+	"crash >f",
+	"Print the panic, fatal error or signal reported ahead of the goroutines,",
+	"with the faulting address and the goroutine that crashed, then the",
+	"goroutines grouped as by trim.",
+}, "\n")
+
+var whoHelp = strings.Join([]string{
+	"who <addr> >f",
+	"List the goroutines that were passed the object at address addr, as the",
+	"receiver of a method, such as sync.(*Mutex).Lock, or as the channel or map",
+	"of a runtime function, with the calls they passed it to. On a merged",
+	"dump, select the instance the address is from as addr@instance.",
+}, "\n")
+
+var holdersHelp = strings.Join([]string{
+	"holders >f",
+	"List the locks goroutines wait for, the most waited for first, with the",
+	"calls waiting for each and the goroutines that probably hold it: those",
+	"passed the lock, or the object whose method acquires it, outside of a",
+	"Lock call. Holders running, in a syscall or waiting for IO are listed",
+	"first, and holders waiting for another lock last.",
+}, "\n")
+
+var channelsHelp = strings.Join([]string{
+	"channels >f",
+	"List the channels goroutines are blocked sending to or receiving from, the",
+	"most blocked on first, then warn of probable leaks: channels at a known",
+	"address with only senders or only receivers blocked for at least the",
+	"minutes option, and operations on nil channels and empty selects, which",
+	"block forever.",
+}, "\n")
+
+var waitsHelp = strings.Join([]string{
+	"waits >f",
+	"List the sync.WaitGroups, sync.Conds, golang.org/x/sync semaphores and",
+	"errgroups goroutines wait on, with the goroutines that may end the wait:",
+	"those the waiters started, and those passed the object or the owner of",
+	"the wait. Warn of the objects waited on for the minutes option or more",
+	"that no goroutine was found to call Done, Signal or Release on.",
+}, "\n")
+
+var findingsHelp = strings.Join([]string{
+	"findings >f",
+	"List the crash, lock cycles, leak suspects and contention hotspots found,",
+	"the most severe first, with the calls of the goroutines involved. Each",
+	"is scored from its kind, the number of goroutines involved and their",
+	"longest wait: critical from 150, high from 100 and medium from 60.",
+	"Leaks, and lone waits for a lock no holder was found for, are only",
+	"reported past the minutes option.",
+	"On a merged dump, each instance is analysed apart.",
+}, "\n")
+
+var diffHelp = strings.Join([]string{
+	"diff >f",
+	"Compare the goroutines with those of the dump set by -base, or else of the",
+	"snapshot before the one reported on. Goroutines are grouped by reason,",
+	"the functions and lines of their calls, creator and labels, and the new",
+	"groups, the changed ones and the vanished ones are listed, the most grown",
+	"first, with their goroutines and longest wait in either dump.",
+}, "\n")
+
+var trendHelp = strings.Join([]string{
+	"trend >f",
+	"Track the goroutines and longest wait of each group over the snapshots",
+	"read, ordered as the dumps given or the snapshots of a file, and fit the",
+	"growth of each group per minute, or per snapshot unless every snapshot",
+	"is timed. Groups whose goroutines never decrease, or whose longest wait",
+	"never does, and grow overall, are reported as leak suspects.",
+}, "\n")
+
+var clusterHelp = strings.Join([]string{
+	"cluster >f",
+	"Cluster the goroutine groups blocked for the same reason whose stacks are",
+	"at least the similarity option similar, such as a handler reached",
+	"through different middleware. Each cluster of several groups is printed",
+	"with the stack of its largest group, and the calls the stacks of the",
+	"others have more (+) or less (-), with their goroutines.",
+}, "\n")
+
+var snapshotsHelp = strings.Join([]string{
+	"snapshots >f",
+	"List the snapshots read, in the order they were taken, with the process",
+	"dumped and the time of the dump when known. A file may hold several dumps",
+	"of a process appended over time, and an archive a dump per member.",
+	"The snapshot reported on by other commands is selected by snapshot=n.",
+}, "\n")
+
 func helpText(s ...string) string {
 	return strings.Join(s, "\n") + "\n"
 }
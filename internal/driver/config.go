@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/shippomx/grains/dump"
+	"github.com/shippomx/grains/internal/report"
 )
 
 // config holds settings for a single named config.
@@ -18,13 +21,31 @@ type config struct {
 	// Display options.
 	SourcePath string `json:"-"`
 	TrimPath   string `json:"-"`
+
+	// Filtering options.
+	Depth    int `json:"depth"`
+	Snapshot int `json:"snapshot"`
+	Minutes  int `json:"minutes"`
+
+	// Grouping options.
+	Group       string  `json:"group"`
+	GroupFrames int     `json:"group_frames"`
+	Similarity  float64 `json:"similarity"`
+
+	// Analysis options.
+	Locks string `json:"locks"`
+	Merge bool   `json:"merge"`
 }
 
 // defaultConfig returns the default configuration values; it is unaffected by
 // flags and interactive assignments.
 func defaultConfig() config {
 	return config{
-		SourcePath: "./",
+		SourcePath:  "./",
+		Minutes:     10,
+		Group:       "exact",
+		GroupFrames: 3,
+		Similarity:  0.8,
 	}
 }
 
@@ -74,7 +95,8 @@ func init() {
 	// choices holds the list of allowed values for config fields that can
 	// take on one of a bounded set of values.
 	choices := map[string][]string{
-		"sort": {"cum", "flat"},
+		"sort":  {"cum", "flat"},
+		"group": {"exact", "ignore_lines", "functions", "top", "bottom", "package"},
 	}
 
 	def := defaultConfig()
@@ -235,3 +257,14 @@ func (cfg *config) resetTransient() {
 	cfg.SourcePath = current.SourcePath
 	cfg.TrimPath = current.TrimPath
 }
+
+// reportOptions returns the report options selected by cfg.
+func reportOptions(cfg config) *report.Options {
+	return &report.Options{
+		Depth:      cfg.Depth,
+		Snapshot:   cfg.Snapshot,
+		Minutes:    cfg.Minutes,
+		Grouping:   dump.Grouping{Strategy: cfg.Group, Frames: cfg.GroupFrames},
+		Similarity: cfg.Similarity,
+	}
+}
@@ -3,6 +3,7 @@ package driver
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"github.com/shippomx/grains/dump"
 	"github.com/shippomx/grains/internal/plugin"
 	"github.com/shippomx/grains/internal/report"
@@ -23,23 +24,30 @@ func Grains(eo *plugin.Options) error {
 		return err
 	}
 
-	p, err := fetchDumps(src, o)
+	if src.Options, err = dumpOptions(currentConfig()); err != nil {
+		return err
+	}
+
+	ps, bases, err := fetchDumps(src, o)
 	if err != nil {
 		return err
 	}
 
-	if p == nil {
+	if len(ps) == 0 {
+		return errors.New("No such file " + src.Sources[0])
+	}
+	if len(src.Base) > 0 && len(bases) == 0 {
 		return errors.New("No such file " + src.Base[0])
 	}
 
 	if cmd != nil {
-		return generateReport(p, cmd, currentConfig(), o)
+		return generateReport(ps, bases, cmd, currentConfig(), o)
 	}
 
-	return interactive(p, o)
+	return interactive(ps, bases, o)
 }
 
-func generateRawReport(p *dump.Dump, cmd []string) (c *command, rpt *report.Report, err error) {
+func generateRawReport(ps, bases []*dump.Dump, cmd []string, cfg config) (c *command, rpt *report.Report, err error) {
 	// Get report output format
 	c = grainsCommands[cmd[0]]
 	if c == nil {
@@ -47,14 +55,68 @@ func generateRawReport(p *dump.Dump, cmd []string) (c *command, rpt *report.Repo
 		return
 	}
 
-	ro := &report.Options{}
-	rpt = report.New(p, ro)
+	// Lock patterns may have been changed since the dumps were parsed.
+	opts, err := dumpOptions(cfg)
+	if err != nil {
+		return
+	}
+	for _, p := range append(append([]*dump.Dump(nil), ps...), bases...) {
+		p.SetOptions(opts)
+	}
+
+	if cfg.Merge {
+		if ps, bases, err = mergeDumps(ps, bases); err != nil {
+			return
+		}
+	}
+
+	rpt, err = report.NewSeries(ps, reportOptions(cfg))
+	// The latest snapshot of the base dumps is the one compared with.
+	if err == nil && len(bases) > 0 {
+		rpt.SetBase(bases[len(bases)-1])
+	}
 
 	return c, rpt, err
 }
 
-func generateReport(p *dump.Dump, cmd []string, cfg config, o *plugin.Options) error {
-	c, rpt, err := generateRawReport(p, cmd)
+// mergeDumps merges the dumps and the base dumps, if any, each into a
+// fleet-wide dump.
+func mergeDumps(ps, bases []*dump.Dump) ([]*dump.Dump, []*dump.Dump, error) {
+	p, err := combineDumps(ps)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bases) == 0 {
+		return []*dump.Dump{p}, nil, nil
+	}
+	base, err := combineDumps(bases)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []*dump.Dump{p}, []*dump.Dump{base}, nil
+}
+
+// dumpOptions returns the options dumps are parsed and analysed with:
+// the lock patterns of the file of the locks option, if any, to
+// recognise locks with in addition to the built-in patterns.
+func dumpOptions(cfg config) (dump.Options, error) {
+	if cfg.Locks == "" {
+		return dump.Options{}, nil
+	}
+	f, err := os.Open(cfg.Locks)
+	if err != nil {
+		return dump.Options{}, err
+	}
+	defer f.Close()
+	patterns, err := dump.ReadLockPatterns(f)
+	if err != nil {
+		return dump.Options{}, fmt.Errorf("%s: %v", cfg.Locks, err)
+	}
+	return dump.Options{LockPatterns: patterns}, nil
+}
+
+func generateReport(ps, bases []*dump.Dump, cmd []string, cfg config, o *plugin.Options) error {
+	c, rpt, err := generateRawReport(ps, bases, cmd, cfg)
 	if err != nil {
 		return err
 	}
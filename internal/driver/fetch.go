@@ -1,18 +1,22 @@
 package driver
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/shippomx/grains/dump"
 	"github.com/shippomx/grains/internal/plugin"
+	"io"
 	"os"
 	"sync"
 )
 
-// fetchDumps fetches and symbolizes the dumps specified by s.
-// It will merge all the dumps it is able to retrieve, even if
-// there are some failures. It will return an error if it is unable to
-// fetch any dumps.
-func fetchDumps(s *source, o *plugin.Options) (*dump.Dump, error) {
+// fetchDumps fetches the dumps specified by s and its base dumps, in the
+// order of the sources, archive members and snapshots they were read
+// from. It returns all the dumps it is able to retrieve, even if there
+// are some failures.
+func fetchDumps(s *source, o *plugin.Options) ([]*dump.Dump, []*dump.Dump, error) {
 	sources := make([]dumpSource, 0, len(s.Sources))
 	for _, src := range s.Sources {
 		sources = append(sources, dumpSource{
@@ -29,17 +33,16 @@ func fetchDumps(s *source, o *plugin.Options) (*dump.Dump, error) {
 		})
 	}
 
-	p, _, _, err := grabSourcesAndBases(sources, bases, o.UI)
+	psrc, pbase, _, err := grabSourcesAndBases(sources, bases, o.UI)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	return p, nil
+	return psrc, pbase, nil
 }
 
-func grabSourcesAndBases(sources, bases []dumpSource, ui plugin.UI) (*dump.Dump, *dump.Dump, bool, error) {
+func grabSourcesAndBases(sources, bases []dumpSource, ui plugin.UI) ([]*dump.Dump, []*dump.Dump, bool, error) {
 	wg := sync.WaitGroup{}
-	var psrc, pbase *dump.Dump
+	var psrc, pbase []*dump.Dump
 	var savesrc, savebase bool
 	var errsrc, errbase error
 	var countsrc, countbase int
@@ -55,7 +58,7 @@ func grabSourcesAndBases(sources, bases []dumpSource, ui plugin.UI) (*dump.Dump,
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			psrc, savesrc, countsrc, errsrc = chunkedGrab(bases, ui)
+			pbase, savebase, countbase, errbase = chunkedGrab(bases, ui)
 		}()
 	}
 	wg.Wait()
@@ -74,10 +77,10 @@ func grabSourcesAndBases(sources, bases []dumpSource, ui plugin.UI) (*dump.Dump,
 	return psrc, pbase, save, nil
 }
 
-// chunkedGrab fetches the dumps described in source and merges them into
-// a single dump. It fetches a chunk of dumps concurrently, with a maximum
-// chunk size to limit its memory usage.
-func chunkedGrab(sources []dumpSource, ui plugin.UI) (p *dump.Dump, save bool, count int, chunkErr error) {
+// chunkedGrab fetches the dumps described in source, in the order of the
+// sources. It fetches a chunk of dumps concurrently, with a maximum chunk
+// size to limit its memory usage.
+func chunkedGrab(sources []dumpSource, ui plugin.UI) (ps []*dump.Dump, save bool, count int, chunkErr error) {
 	const chunkSize = 64
 
 	for start := 0; start < len(sources); start += chunkSize {
@@ -85,17 +88,23 @@ func chunkedGrab(sources []dumpSource, ui plugin.UI) (p *dump.Dump, save bool, c
 		if end > len(sources) {
 			end = len(sources)
 		}
-		p, save, count, chunkErr = concurrentGrab(sources[start:end], ui)
+		chunkP, chunkSave, chunkCount, chunkErr := concurrentGrab(sources[start:end], ui)
 		if chunkErr != nil {
 			return nil, false, 0, chunkErr
 		}
+		ps = append(ps, chunkP...)
+		save = save || chunkSave
+		count += chunkCount
 	}
 
-	return p, save, count, nil
+	return ps, save, count, nil
 }
 
-// concurrentGrab fetches multiple dumps concurrently
-func concurrentGrab(sources []dumpSource, ui plugin.UI) (*dump.Dump, bool, int, error) {
+// concurrentGrab fetches multiple dumps concurrently. It returns the
+// dumps fetched, in the order of the sources, and the number of sources
+// fetched, which differs from the number of dumps when sources are
+// archives.
+func concurrentGrab(sources []dumpSource, ui plugin.UI) ([]*dump.Dump, bool, int, error) {
 	wg := sync.WaitGroup{}
 	wg.Add(len(sources))
 	for i := range sources {
@@ -107,13 +116,20 @@ func concurrentGrab(sources []dumpSource, ui plugin.UI) (*dump.Dump, bool, int,
 	wg.Wait()
 
 	var save bool
+	var count int
 	dumps := make([]*dump.Dump, 0, len(sources))
 	for _, s := range sources {
 		if err := s.err; err != nil {
 			ui.PrintErr(s.addr + ": " + err.Error())
 			continue
 		}
-		dumps = append(dumps, s.p)
+		for _, p := range s.p {
+			if p.Truncated > 0 {
+				ui.PrintErr(fmt.Sprintf("%s: %d lines too long, cut", p.Source.Name, p.Truncated))
+			}
+		}
+		dumps = append(dumps, s.p...)
+		count++
 	}
 
 	if len(dumps) == 0 {
@@ -124,18 +140,34 @@ func concurrentGrab(sources []dumpSource, ui plugin.UI) (*dump.Dump, bool, int,
 	//if err != nil {
 	//	return nil, false, 0, err
 	//}
-	return sources[0].p, save, len(dumps), nil
+	return dumps, save, count, nil
 }
 
+// combineDumps merges the latest snapshot of each source of dumps into a
+// fleet-wide dump, taking each source for an instance of the program
+// dumped.
 func combineDumps(dumps []*dump.Dump) (*dump.Dump, error) {
-	return nil, nil
+	var latest []*dump.Dump
+	index := make(map[string]int)
+	for _, p := range dumps {
+		if i, ok := index[p.Source.Name]; ok {
+			latest[i] = p
+			continue
+		}
+		index[p.Source.Name] = len(latest)
+		latest = append(latest, p)
+	}
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no dump to merge")
+	}
+	return dump.Merge(latest), nil
 }
 
 type dumpSource struct {
 	addr   string
 	source *source
 
-	p   *dump.Dump
+	p   []*dump.Dump
 	err error
 }
 
@@ -159,22 +191,85 @@ func setTmpDir(ui plugin.UI) (string, error) {
 	return "", fmt.Errorf("failed to identify temp dir")
 }
 
-// grabDump fetches a dump. Returns the dump, sources for the
-// dump mappings, a bool indicating if the dump was fetched
-// remotely, and an error.
-func grabDump(s *source, source string) (p *dump.Dump, err error) {
-	return fetch(source)
+// grabDump fetches the dumps of a source. Returns the dumps, one per
+// member if the source is an archive, and an error.
+func grabDump(s *source, source string) (p []*dump.Dump, err error) {
+	return fetch(source, s.Options)
 }
 
-// fetch fetches a dump from source, within the timeout specified,
-// producing messages through the ui. It returns the dump and the
-// url of the actual source of the dump for remote dumps.
-func fetch(source string) (p *dump.Dump, err error) {
+// fetch fetches the dumps stored in source, which may be compressed,
+// parsing them with options o. A tar archive holds a dump per regular
+// file, labelled with the name of the archive followed by the name of
+// the member, and a file may hold several snapshots.
+func fetch(source string, o dump.Options) (p []*dump.Dump, err error) {
 	f, err := os.Open(source)
-	if err == nil {
-		defer f.Close()
-		p = dump.NewDump()
-		err = p.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := dump.Decompress(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(r)
+	if header, _ := br.Peek(tarHeaderSize); !isTar(header) {
+		return parseDumps(br, source, o)
+	}
+
+	var memberErr error
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Size == 0 {
+			continue
+		}
+		// Archives may bundle files other than dumps, such as logs or
+		// metadata; a member is only an error if no member is a dump.
+		d, err := parseDumps(tr, source+":"+hdr.Name, o)
+		if err != nil {
+			if memberErr == nil {
+				memberErr = fmt.Errorf("%s: %v", hdr.Name, err)
+			}
+			continue
+		}
+		p = append(p, d...)
+	}
+	if len(p) == 0 {
+		if memberErr == nil {
+			memberErr = fmt.Errorf("no dump found in archive")
+		}
+		return nil, memberErr
+	}
+	return p, nil
+}
+
+// parseDumps parses the snapshots read from r with options o and labels
+// them with name.
+func parseDumps(r io.Reader, name string, o dump.Options) ([]*dump.Dump, error) {
+	ps, err := dump.ParseSnapshots(r, o)
+	if err != nil {
+		return nil, err
 	}
-	return
+	for _, p := range ps {
+		p.Source.Name = name
+	}
+	return ps, nil
+}
+
+// tarHeaderSize is the size of the header of a tar archive member.
+const tarHeaderSize = 512
+
+// isTar reports whether header is the header of a POSIX or GNU tar
+// archive member, which carry the "ustar" magic at offset 257.
+func isTar(header []byte) bool {
+	return len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar"))
 }
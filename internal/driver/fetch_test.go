@@ -0,0 +1,106 @@
+package driver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shippomx/grains/dump"
+)
+
+const fetchDump = `goroutine 1 [running]:
+main.main()
+	/src/app/main.go:7 +0x1d
+
+goroutine 5 [chan receive, 3 minutes]:
+main.worker(0xc000010000)
+	/src/app/main.go:12 +0x45
+created by main.main in goroutine 1
+	/src/app/main.go:8 +0x4a
+`
+
+type member struct {
+	name string
+	data []byte
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func archive(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for _, m := range members {
+		if err := w.WriteHeader(&tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(m.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestFetchArchive(t *testing.T) {
+	members := archive(t,
+		member{"notes.txt", []byte("collected on host-1\n")},
+		member{"host-1.txt.gz", gzipped(t, []byte(fetchDump))},
+	)
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"dumps.tar", members},
+		{"dumps.tar.gz", gzipped(t, members)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), tc.name)
+			if err := ioutil.WriteFile(source, tc.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := fetch(source, dump.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ps) != 1 {
+				t.Fatalf("got %d dumps, want the one of host-1.txt.gz", len(ps))
+			}
+			if want := source + ":host-1.txt.gz"; ps[0].Source.Name != want {
+				t.Errorf("got dump named %q, want %q", ps[0].Source.Name, want)
+			}
+			if got := len(ps[0].Goroutines); got != 2 {
+				t.Errorf("got %d goroutines, want 2", got)
+			}
+		})
+	}
+}
+
+func TestFetchArchiveWithoutDump(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "notes.tar")
+	data := archive(t, member{"notes.txt", []byte("collected on host-1\n")})
+	if err := ioutil.WriteFile(source, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := fetch(source, dump.Options{})
+	if err == nil || !strings.HasPrefix(err.Error(), "notes.txt:") {
+		t.Errorf("got error %v, want the error of notes.txt", err)
+	}
+}
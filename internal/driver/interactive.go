@@ -14,15 +14,16 @@ import (
 var commentStart = "//:" // Sentinel for comments on options
 var tailDigitsRE = regexp.MustCompile("[0-9]+$")
 
-// interactive starts a shell to read grains commands.
-func interactive(p *dump.Dump, o *plugin.Options) error {
+// interactive starts a shell to read grains commands on the dumps ps,
+// compared with the base dumps bases.
+func interactive(ps, bases []*dump.Dump, o *plugin.Options) error {
 	// Do not wait for the visualizer to complete, to allow multiple
 	// graphs to be visualized simultaneously.
 
 	shortcuts := shortcuts{
 		":": []string{"list="},
 	}
-	greetings(p, o.UI)
+	greetings(ps, o.UI)
 	for {
 		input, err := o.UI.ReadLine("(grains) ")
 		if err != nil {
@@ -37,7 +38,7 @@ func interactive(p *dump.Dump, o *plugin.Options) error {
 		for _, input := range shortcuts.expand(input) {
 			// Process assignments of the form variable=value
 			if s := strings.SplitN(input, "=", 2); len(s) > 0 {
-				//name := strings.TrimSpace(s[0])
+				name := strings.TrimSpace(s[0])
 				var value string
 				if len(s) == 2 {
 					value = s[1]
@@ -46,17 +47,17 @@ func interactive(p *dump.Dump, o *plugin.Options) error {
 					}
 					value = strings.TrimSpace(value)
 				}
-				//if isConfigurable(name) {
-				//	// All non-bool options require inputs
-				//	if len(s) == 1 && !isBoolConfig(name) {
-				//		o.UI.PrintErr(fmt.Errorf("please specify a value, e.g. %s=<val>", name))
-				//		continue
-				//	}
-				//	if err := configure(name, value); err != nil {
-				//		o.UI.PrintErr(err)
-				//	}
-				//	continue
-				//}
+				if isConfigurable(name) {
+					// All non-bool options require inputs
+					if len(s) == 1 && !isBoolConfig(name) {
+						o.UI.PrintErr(fmt.Errorf("please specify a value, e.g. %s=<val>", name))
+						continue
+					}
+					if err := configure(name, value); err != nil {
+						o.UI.PrintErr(err)
+					}
+					continue
+				}
 			}
 
 			tokens := strings.Fields(input)
@@ -66,7 +67,7 @@ func interactive(p *dump.Dump, o *plugin.Options) error {
 
 			switch tokens[0] {
 			case "o", "options":
-				printCurrentOptions(p, o.UI)
+				printCurrentOptions(o.UI)
 				continue
 			case "exit", "quit", "q":
 				return nil
@@ -77,7 +78,7 @@ func interactive(p *dump.Dump, o *plugin.Options) error {
 
 			args, cfg, err := parseCommandLine(tokens)
 			if err == nil {
-				err = generateReportWrapper(p, args, cfg, o)
+				err = generateReportWrapper(ps, bases, args, cfg, o)
 			}
 
 			if err != nil {
@@ -91,12 +92,15 @@ var generateReportWrapper = generateReport // For testing purposes.
 
 // greetings prints a brief welcome and some overall dump
 // information before accepting interactive commands.
-func greetings(p *dump.Dump, ui plugin.UI) {
+func greetings(ps []*dump.Dump, ui plugin.UI) {
 	//numLabelUnits := identifyNumLabelUnits(p, ui)
 	//ropt, err := reportOptions(p, numLabelUnits, currentConfig())
 	//if err == nil {
 	//	rpt := report.New(p, ropt)
 	//}
+	if len(ps) > 1 {
+		ui.Print(fmt.Sprintf("Read %d snapshots, reporting on the latest (type \"snapshots\" to list them)", len(ps)))
+	}
 	ui.Print(`Entering interactive mode (type "help" for commands, "o" for options)`)
 }
 
@@ -114,7 +118,7 @@ func (a shortcuts) expand(input string) []string {
 	return []string{input}
 }
 
-func printCurrentOptions(p *dump.Dump, ui plugin.UI) {
+func printCurrentOptions(ui plugin.UI) {
 	var args []string
 	current := currentConfig()
 	for _, f := range configFields {
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printChannels lists the channels goroutines are blocked on, with the
+// calls blocked sending and receiving, then warns of the channels that
+// probably leak the goroutines blocked on them.
+func printChannels(w io.Writer, rpt *Report) {
+	if printInstances(w, rpt, printChannels) {
+		return
+	}
+	channels := rpt.prof.Channels()
+	for _, c := range channels {
+		fmt.Fprintf(w, "================= %s, %d senders, %d receivers =================\n", chanName(c), len(c.Senders), len(c.Receivers))
+		printCalls(w, rpt, "sending", chanCalls(c.Senders))
+		printCalls(w, rpt, "receiving", chanCalls(c.Receivers))
+	}
+
+	orphaned, forever := chanLeaks(rpt)
+	if len(orphaned) > 0 {
+		fmt.Fprintf(w, "================= WARNING CHANNEL LEAK =================\n")
+		fmt.Fprintf(w, "channels with only senders or only receivers blocked for %d minutes or more\n", rpt.options.Minutes)
+	}
+	for _, c := range orphaned {
+		if len(c.Senders) > 0 {
+			fmt.Fprintf(w, "%s has no receiver blocked\n", chanName(c))
+			printCalls(w, rpt, "sending", chanCalls(c.Senders))
+		} else {
+			fmt.Fprintf(w, "%s has no sender blocked\n", chanName(c))
+			printCalls(w, rpt, "receiving", chanCalls(c.Receivers))
+		}
+	}
+
+	if len(forever) > 0 {
+		fmt.Fprintf(w, "================= WARNING CHANNEL LEAK =================\n")
+		fmt.Fprintf(w, "blocked forever on a nil channel or an empty select\n")
+		printCalls(w, rpt, "blocked", chanCalls(forever))
+	}
+	if len(channels) == 0 && len(forever) == 0 {
+		fmt.Fprintf(w, "no goroutine is blocked on a channel\n")
+	}
+}
+
+// chanLeaks returns the channels with only senders or only receivers
+// blocked for the minutes option or more, and the goroutines blocked
+// forever on a nil channel or an empty select. Goroutines blocked in a
+// select are not, as the channels of its cases are not printed: any may
+// still become ready.
+func chanLeaks(rpt *Report) (orphaned []*dump.Channel, forever []*dump.ChanWait) {
+	for _, c := range rpt.prof.Channels() {
+		if c.Orphaned(rpt.options.Minutes) {
+			orphaned = append(orphaned, c)
+		}
+	}
+	for _, wait := range rpt.prof.ChanWaits() {
+		if wait.Forever {
+			forever = append(forever, wait)
+		}
+	}
+	return orphaned, forever
+}
+
+// chanName names a channel by its address, or by the address of its
+// owner when the address of the channel is unknown, which may own
+// several channels.
+func chanName(c *dump.Channel) string {
+	if c.ByOwner {
+		return fmt.Sprintf("chans of %#x", c.Addr)
+	}
+	return fmt.Sprintf("chan %#x", c.Addr)
+}
+
+// chanCalls returns the calls goroutines are blocked on a channel in.
+func chanCalls(waits []*dump.ChanWait) []dump.Occurrence {
+	var occurrences []dump.Occurrence
+	for _, wait := range waits {
+		occurrences = append(occurrences, dump.Occurrence{GID: wait.GID, Stack: wait.Stack})
+	}
+	return occurrences
+}
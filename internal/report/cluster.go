@@ -0,0 +1,39 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printClusters clusters the goroutine groups whose stacks are similar,
+// printing the stack of the largest group of each cluster of several
+// groups with the calls the others differ by, and counts the groups
+// unlike any other.
+func printClusters(w io.Writer, rpt *Report) {
+	groups := rpt.groups()
+	clusters := dump.Clusters(groups, rpt.options.Similarity)
+	fmt.Fprintf(w, "================= %d clusters of %d groups, similarity %.2f =================\n", len(clusters), len(groups), rpt.options.Similarity)
+	single := 0
+	for _, c := range clusters {
+		if len(c.Groups) == 1 {
+			single++
+			continue
+		}
+		r := c.Representative
+		fmt.Fprintf(w, "%d goroutines in %d groups: [%s] %s\n", c.Count, len(c.Groups), r.Reason, groupCall(&r.Frame))
+		for i := range r.Stacks {
+			printCall(w, &r.Frame, i)
+		}
+		printCreator(w, r.Creator)
+		for _, v := range c.Variants {
+			sign := "+"
+			if v.Missing {
+				sign = "-"
+			}
+			fmt.Fprintf(w, "\t%s %s, %d goroutines\n", sign, v.FuncName, v.Count)
+		}
+	}
+	fmt.Fprintf(w, "%d groups unlike any other\n", single)
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printCrash summarises the crash that made the process print its
+// goroutines, and the goroutine that crashed, of every instance of a
+// merged dump, ahead of the goroutines grouped as by trim.
+func printCrash(w io.Writer, rpt *Report) {
+	if !printInstances(w, rpt, printCrashed) {
+		printCrashed(w, rpt)
+	}
+	trimStacks(w, rpt)
+}
+
+// printCrashed summarises the crash of a dump and its crashed goroutine.
+func printCrashed(w io.Writer, rpt *Report) {
+	c := rpt.prof.Crash
+	fmt.Fprintf(w, "================= Crash =================\n")
+	if c == nil {
+		fmt.Fprintf(w, "no crash reported, the goroutines were dumped on request\n")
+	} else {
+		kind := c.Kind
+		if kind == dump.CrashSignal {
+			kind = c.Signal
+		}
+		fmt.Fprintf(w, "%s: %s\n", kind, strings.Replace(c.Message, "\n", "\n\t", -1))
+		if c.Signal != "" {
+			var details []string
+			if c.Code != "" {
+				details = append(details, "code="+c.Code)
+			}
+			if c.HasAddr {
+				details = append(details, fmt.Sprintf("addr=%#x", c.Addr))
+			}
+			if c.PC != 0 {
+				details = append(details, fmt.Sprintf("pc=%#x", c.PC))
+			}
+			fmt.Fprintf(w, "signal: %s %s\n", c.Signal, strings.Join(details, " "))
+		}
+		if c.GID > 0 {
+			printFrame(w, rpt, strconv.Itoa(c.GID))
+		} else {
+			fmt.Fprintf(w, "no goroutine was running\n")
+		}
+	}
+}
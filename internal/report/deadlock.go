@@ -0,0 +1,21 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printCall prints the call of f at index i of its stacks, or the
+// innermost call if there is none.
+func printCall(w io.Writer, f *dump.Frame, i int) {
+	if f == nil || len(f.Stacks) == 0 {
+		return
+	}
+	if i >= len(f.Stacks) {
+		i = len(f.Stacks) - 1
+	}
+	s := f.Stacks[i]
+	fmt.Fprintf(w, "\t%s(%s)\n\t\t%s\n", s.FuncName, s.Params, s.Location)
+}
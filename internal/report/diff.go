@@ -0,0 +1,95 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// SetBase sets the dump the diff report compares the reported snapshot
+// with. Without a base, it is compared with the snapshot before it.
+func (rpt *Report) SetBase(base *dump.Dump) {
+	rpt.base = base
+}
+
+// diffBase returns the dump to compare the reported snapshot with, or
+// nil.
+func (rpt *Report) diffBase() *dump.Dump {
+	if rpt.base != nil {
+		return rpt.base
+	}
+	if rpt.current > 0 {
+		return rpt.series[rpt.current-1]
+	}
+	return nil
+}
+
+// printDiff compares the goroutine groups of the reported snapshot with
+// those of the base dump, listing the new groups, the vanished ones and
+// the ones whose goroutines or longest wait changed, the most grown
+// first.
+func printDiff(w io.Writer, rpt *Report) {
+	base := rpt.diffBase()
+	if base == nil {
+		fmt.Fprintf(w, "no dump to compare with, set one with -base\n")
+		return
+	}
+	var added, vanished, changed []*dump.GroupDiff
+	unchanged := 0
+	for _, d := range dump.Diff(base, rpt.prof) {
+		switch {
+		case d.Base == nil:
+			added = append(added, d)
+		case d.Target == nil:
+			vanished = append(vanished, d)
+		case d.Growth() != 0 || d.Minutes != d.BaseMinutes:
+			changed = append(changed, d)
+		default:
+			unchanged++
+		}
+	}
+
+	fmt.Fprintf(w, "================= Diff against %s =================\n", snapshotName(base))
+	n, baseN := goroutines(rpt.prof), goroutines(base)
+	fmt.Fprintf(w, "goroutines: %d -> %d (%+d)\n", baseN, n, n-baseN)
+	fmt.Fprintf(w, "groups: %d new, %d vanished, %d changed, %d unchanged\n", len(added), len(vanished), len(changed), unchanged)
+	for _, section := range []struct {
+		name  string
+		diffs []*dump.GroupDiff
+	}{
+		{"new groups", added},
+		{"changed groups", changed},
+		{"vanished groups", vanished},
+	} {
+		if len(section.diffs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "[%s]:\n", section.name)
+		for _, d := range section.diffs {
+			printGroupDiff(w, d)
+		}
+	}
+}
+
+// printGroupDiff prints the change of a group and its stack.
+func printGroupDiff(w io.Writer, d *dump.GroupDiff) {
+	tf := d.Target
+	if tf == nil {
+		tf = d.Base
+	}
+	fmt.Fprintf(w, "%+d goroutines (%d -> %d), longest wait %d -> %d minutes: [%s]\n",
+		d.Growth(), d.BaseCount, d.Count, d.BaseMinutes, d.Minutes, tf.Reason)
+	for i := range tf.Stacks {
+		printCall(w, &tf.Frame, i)
+	}
+	printCreator(w, tf.Creator)
+}
+
+// snapshotName names a snapshot by its source, and its time if known.
+func snapshotName(p *dump.Dump) string {
+	if p.Source.Time.IsZero() {
+		return p.Source.Name
+	}
+	return fmt.Sprintf("%s at %s", p.Source.Name, snapshotTime(p.Source))
+}
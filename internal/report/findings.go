@@ -0,0 +1,372 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// Finding kinds.
+const (
+	FindingCrash      = "crash"
+	FindingDeadlock   = "deadlock"
+	FindingLeak       = "leak suspect"
+	FindingContention = "contention hotspot"
+)
+
+// Finding is a problem found in a dump, with the calls it was found in.
+type Finding struct {
+	dump.Finding
+
+	verb  string            // what the goroutines do in their calls
+	calls []dump.Occurrence // calls the goroutines are in
+}
+
+// Base severities of the findings of each kind, from the most certain,
+// a crash, to the least, as contention may be transient.
+var findingBase = map[string]float64{
+	FindingCrash:      150,
+	FindingDeadlock:   100,
+	FindingLeak:       40,
+	FindingContention: 30,
+}
+
+// severity scores a finding from its kind, evidence and number of
+// goroutines involved and their longest wait: each doubling of the
+// goroutines adds 10, and each doubling of the minutes adds 3.
+func severity(base float64, goroutines, minutes int) int {
+	return int(math.Round(base + 10*math.Log2(1+float64(goroutines)) + 3*math.Log2(1+float64(minutes))))
+}
+
+// Level names the severity of a finding.
+func (f *Finding) Level() string {
+	switch {
+	case f.Severity >= 150:
+		return "critical"
+	case f.Severity >= 100:
+		return "high"
+	case f.Severity >= 60:
+		return "medium"
+	}
+	return "low"
+}
+
+// newFinding returns a finding on the calls of the goroutines involved,
+// scored from base.
+func (rpt *Report) newFinding(kind string, base float64, verb string, calls []dump.Occurrence, summary string, args ...interface{}) Finding {
+	f := Finding{Finding: dump.Finding{Kind: kind, Summary: fmt.Sprintf(summary, args...)}, verb: verb, calls: calls}
+	seen := make(map[int]bool)
+	for _, o := range calls {
+		if seen[o.GID] {
+			continue
+		}
+		seen[o.GID] = true
+		f.GIDs = append(f.GIDs, o.GID)
+		if frame := rpt.prof.GetFrameByGID(o.GID); frame != nil && frame.Duration > f.Minutes {
+			f.Minutes = frame.Duration
+		}
+	}
+	sort.Ints(f.GIDs)
+	f.Severity = severity(base, len(f.GIDs), f.Minutes)
+	return f
+}
+
+// Findings returns the crash, deadlocks, leak suspects and contention
+// hotspots found in the dump, or on each instance of a merged dump, the
+// most severe first.
+func (rpt *Report) Findings() []Finding {
+	var findings []Finding
+	if rpt.prof.Merged() {
+		for i := range rpt.prof.Instances {
+			for _, f := range rpt.instanceReport(i).Findings() {
+				f.Instance = i + 1
+				findings = append(findings, f)
+			}
+		}
+		sortFindings(findings)
+		return findings
+	}
+	g := rpt.waitGraph()
+	deadlocks := rpt.deadlockFindings(g)
+	deadlocked := make(map[int]bool)
+	for _, f := range deadlocks {
+		for _, gid := range f.GIDs {
+			deadlocked[gid] = true
+		}
+	}
+	findings = append(findings, rpt.crashFindings()...)
+	findings = append(findings, deadlocks...)
+	findings = append(findings, rpt.leakFindings()...)
+	findings = append(findings, rpt.contentionFindings(g, deadlocked)...)
+	sortFindings(findings)
+	return findings
+}
+
+// sortFindings sorts findings, the most severe first.
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		if findings[i].Kind != findings[j].Kind {
+			return findingBase[findings[i].Kind] > findingBase[findings[j].Kind]
+		}
+		if findings[i].Instance != findings[j].Instance {
+			return findings[i].Instance < findings[j].Instance
+		}
+		return firstGID(findings[i]) < firstGID(findings[j])
+	})
+}
+
+func firstGID(f Finding) int {
+	if len(f.GIDs) == 0 {
+		return 0
+	}
+	return f.GIDs[0]
+}
+
+// crashFindings returns the crash that made the process print its
+// goroutines. A SIGQUIT is how goroutines are dumped on request, so it is
+// not reported.
+func (rpt *Report) crashFindings() []Finding {
+	c := rpt.prof.Crash
+	if c == nil || c.Signal == "SIGQUIT" {
+		return nil
+	}
+	kind := c.Kind
+	if kind == dump.CrashSignal {
+		kind = c.Signal
+	}
+	var calls []dump.Occurrence
+	if c.GID > 0 && rpt.prof.GetFrameByGID(c.GID) != nil {
+		calls = append(calls, dump.Occurrence{GID: c.GID, Stack: 0})
+	}
+	message := strings.SplitN(c.Message, "\n", 2)[0]
+	return []Finding{rpt.newFinding(FindingCrash, findingBase[FindingCrash], "crashed", calls, "%s: %s", kind, message)}
+}
+
+// deadlockFindings returns the cycles of goroutines waiting for each
+// other's locks in g, and the readers blocked on a lock they read locked.
+func (rpt *Report) deadlockFindings(g *dump.WaitGraph) []Finding {
+	var findings []Finding
+	for _, cycle := range g.Cycles() {
+		var calls []dump.Occurrence
+		var locks []string
+		for _, gid := range cycle {
+			wait := g.Waits[gid]
+			calls = append(calls, dump.Occurrence{GID: gid, Stack: wait.Stack + 1})
+			locks = append(locks, fmt.Sprintf("%#x", wait.Addr))
+		}
+		summary := fmt.Sprintf("goroutines %s wait for each other's locks %s", formatGIDs(cycle), strings.Join(locks, " "))
+		if len(cycle) == 1 {
+			summary = fmt.Sprintf("goroutine %d waits for lock %s it holds", cycle[0], locks[0])
+		}
+		findings = append(findings, rpt.newFinding(FindingDeadlock, findingBase[FindingDeadlock], "waiting", calls, "%s", summary))
+	}
+	for _, r := range rpt.prof.RecursiveRLocks(g) {
+		calls := []dump.Occurrence{{GID: r.GID, Stack: r.Stack + 1}}
+		// Without the pending writer, the reader may wait for an
+		// active one instead.
+		base := findingBase[FindingDeadlock] * 3 / 4
+		summary := fmt.Sprintf("goroutine %d waits to read lock sync.RWMutex %#x it read locked", r.GID, r.Addr)
+		if r.Writer != nil {
+			base = findingBase[FindingDeadlock]
+			calls = append(calls, dump.Occurrence{GID: r.Writer.GID, Stack: r.Writer.Stack + 1})
+			summary += fmt.Sprintf(", behind writer goroutine %d", r.Writer.GID)
+		}
+		findings = append(findings, rpt.newFinding(FindingDeadlock, base, "waiting", calls, "%s", summary))
+	}
+	return findings
+}
+
+// leakFindings returns the goroutines blocked on waits and channels that
+// probably never end, by call they are blocked in. A goroutine is reported
+// once, for its outermost wait, as semaphores and errgroups wait on
+// channels and WaitGroups. Goroutines of the runtime are left out.
+func (rpt *Report) leakFindings() []Finding {
+	var findings []Finding
+	reported := make(map[int]bool)
+	byCall := func(base float64, verb string, calls []dump.Occurrence, summary string) {
+		var unreported []dump.Occurrence
+		for _, o := range calls {
+			if !reported[o.GID] && !runtimeCall(rpt, o) {
+				reported[o.GID] = true
+				unreported = append(unreported, o)
+			}
+		}
+		for _, c := range groupCalls(rpt, unreported) {
+			findings = append(findings, rpt.newFinding(FindingLeak, base, verb, c, "%s in %s", summary, callName(rpt, c[0])))
+		}
+	}
+
+	keys, objects := syncWaits(rpt.prof)
+	var passed map[uint64][]dump.Occurrence
+	if len(keys) > 0 {
+		passed = rpt.prof.PassedWords()
+	}
+	orphans := make(map[string][]dump.Occurrence)
+	var kinds []string
+	for _, key := range keys {
+		waits := objects[key]
+		if !waitedFor(rpt.prof, waits, rpt.options.Minutes) || len(releasers(rpt.prof, passed, waits)) > 0 {
+			continue
+		}
+		kind := key.object + " with no goroutine found to " + waits[0].release
+		if orphans[kind] == nil {
+			kinds = append(kinds, kind)
+		}
+		for _, wait := range waits {
+			orphans[kind] = append(orphans[kind], dump.Occurrence{GID: wait.gid, Stack: wait.stack + 1})
+		}
+	}
+	for _, kind := range kinds {
+		byCall(findingBase[FindingLeak], "waiting", orphans[kind], "waiting on "+kind)
+	}
+
+	orphaned, forever := chanLeaks(rpt)
+	// Blocking on a nil channel or an empty select is certain to last.
+	byCall(findingBase[FindingLeak]*3/2, "blocked", chanCalls(forever), "blocked forever on a nil channel or an empty select")
+	var senders, receivers []dump.Occurrence
+	for _, c := range orphaned {
+		senders = append(senders, chanCalls(c.Senders)...)
+		receivers = append(receivers, chanCalls(c.Receivers)...)
+	}
+	byCall(findingBase[FindingLeak], "sending", senders, "blocked sending with no receiver")
+	byCall(findingBase[FindingLeak], "receiving", receivers, "blocked receiving with no sender")
+	return findings
+}
+
+// contentionFindings returns the locks of g several goroutines wait for,
+// or held by a probable holder, or waited for long, and the writers
+// starved by readers. Locks only deadlocked goroutines wait for are
+// reported as deadlocks already.
+func (rpt *Report) contentionFindings(g *dump.WaitGraph, deadlocked map[int]bool) []Finding {
+	var findings []Finding
+	for _, c := range rpt.prof.Contentions(g) {
+		var calls []dump.Occurrence
+		others := false
+		for _, wait := range c.Waiters {
+			calls = append(calls, dump.Occurrence{GID: wait.GID, Stack: wait.Stack + 1})
+			others = others || !deadlocked[wait.GID]
+		}
+		if !others {
+			continue
+		}
+		f := rpt.newFinding(FindingContention, findingBase[FindingContention], "waiting", calls,
+			"%s for %s %#x", waiters(len(c.Waiters)), c.Kind, c.Addr)
+		// A lone wait for a lock no holder was found for may be about
+		// to end, unless it lasted as long as a leak.
+		if len(c.Waiters) < 2 && len(c.Holders) == 0 && f.Minutes < rpt.options.Minutes {
+			continue
+		}
+		if len(c.Holders) > 0 {
+			f.Summary += fmt.Sprintf(", probably held by goroutine %d", c.Holders[0].GID)
+		}
+		findings = append(findings, f)
+	}
+	for _, s := range rpt.prof.StarvedWriters(g) {
+		calls := []dump.Occurrence{{GID: s.GID, Stack: s.Stack + 1}}
+		f := rpt.newFinding(FindingContention, findingBase[FindingContention], "waiting", calls,
+			"writer goroutine %d starved by the readers of sync.RWMutex %#x, with %d goroutines queued behind it",
+			s.GID, s.Addr, len(s.Lock.Readers)+len(s.Lock.Writers))
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// waiters returns the subject and verb of a sentence on n goroutines
+// waiting.
+func waiters(n int) string {
+	if n == 1 {
+		return "1 goroutine waits"
+	}
+	return fmt.Sprintf("%d goroutines wait", n)
+}
+
+// groupCalls groups calls by function, the most common first.
+func groupCalls(rpt *Report, calls []dump.Occurrence) [][]dump.Occurrence {
+	var names []string
+	groups := make(map[string][]dump.Occurrence)
+	for _, o := range calls {
+		name := callName(rpt, o)
+		if groups[name] == nil {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], o)
+	}
+	sort.SliceStable(names, func(i, j int) bool { return len(groups[names[i]]) > len(groups[names[j]]) })
+	var grouped [][]dump.Occurrence
+	for _, name := range names {
+		grouped = append(grouped, groups[name])
+	}
+	return grouped
+}
+
+// callName returns the function of a call of a goroutine.
+func callName(rpt *Report, o dump.Occurrence) string {
+	if f := rpt.prof.GetFrameByGID(o.GID); f != nil && o.Stack < len(f.Stacks) {
+		return f.Stacks[o.Stack].FuncName
+	}
+	return "unknown"
+}
+
+// runtimeCall reports whether a goroutine blocks in the runtime itself.
+func runtimeCall(rpt *Report, o dump.Occurrence) bool {
+	f := rpt.prof.GetFrameByGID(o.GID)
+	return f != nil && o.Stack < len(f.Stacks) && f.Stacks[o.Stack].ImportPath == "runtime"
+}
+
+// writeProto saves the dump in the format of dump.proto, with the
+// findings of the report.
+func (rpt *Report) writeProto(w io.Writer) error {
+	rpt.prof.Findings = nil
+	for _, f := range rpt.Findings() {
+		rpt.prof.Findings = append(rpt.prof.Findings, f.Finding)
+	}
+	return rpt.prof.Write(w)
+}
+
+// printFindings prints the findings, the most severe first, with the
+// calls of the goroutines involved.
+func printFindings(w io.Writer, rpt *Report) {
+	findings := rpt.Findings()
+	fmt.Fprintf(w, "================= Findings =================\n")
+	if len(findings) == 0 {
+		fmt.Fprintf(w, "no crash, deadlock, leak suspect or contention hotspot found\n")
+		return
+	}
+	for i, f := range findings {
+		fmt.Fprintf(w, "%d. [%s %d] %s: %s", i+1, f.Level(), f.Severity, f.Kind, f.Summary)
+		if f.Minutes > 0 {
+			fmt.Fprintf(w, ", for up to %d minutes", f.Minutes)
+		}
+		ir := rpt
+		if f.Instance > 0 {
+			fmt.Fprintf(w, ", on %s", rpt.instanceName(f.Instance-1))
+			ir = rpt.instanceReport(f.Instance - 1)
+		}
+		fmt.Fprintf(w, "\n")
+		printCalls(w, ir, f.verb, f.calls)
+	}
+}
+
+// printFindingsSummary prints one line per finding, the most severe
+// first.
+func printFindingsSummary(w io.Writer, rpt *Report) {
+	findings := rpt.Findings()
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Fprint(w, "[findings]:\n")
+	for _, f := range findings {
+		fmt.Fprintf(w, "[%s %d] %s: %s", f.Level(), f.Severity, f.Kind, f.Summary)
+		if f.Instance > 0 {
+			fmt.Fprintf(w, ", on %s", rpt.instanceName(f.Instance-1))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
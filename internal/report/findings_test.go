@@ -0,0 +1,53 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/shippomx/grains/dump"
+)
+
+func TestContentionFindings(t *testing.T) {
+	// Goroutine 5 waits for the Mutex of a Store a minute, probably
+	// held by goroutine 6, and goroutine 7 waits for another Mutex no
+	// holder was found for.
+	const text = `goroutine 5 [sync.Mutex.Lock, 1 minutes]:
+internal/sync.runtime_SemacquireMutex(0xc000010008?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+sync.(*Mutex).Lock(0xc000010000)
+	/usr/local/go/src/sync/mutex.go:46 +0x48
+main.(*Store).Set(0xc000010000, 0x1)
+	/src/app/main.go:20 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:40 +0x1f6
+
+goroutine 6 [IO wait, 1 minutes]:
+internal/poll.runtime_pollWait(0x7f2c1c4af108, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:351 +0x85
+main.(*Store).Flush(0xc000010000)
+	/src/app/main.go:30 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:41 +0x1f6
+
+goroutine 7 [sync.Mutex.Lock, 1 minutes]:
+internal/sync.runtime_SemacquireMutex(0xc000020008?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+sync.(*Mutex).Lock(0xc000020000)
+	/usr/local/go/src/sync/mutex.go:46 +0x48
+main.(*Cache).Put(0xc000020000, 0x1)
+	/src/app/main.go:50 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:42 +0x1f6
+`
+	p := dump.NewDump()
+	if err := p.ParseData(text); err != nil {
+		t.Fatal(err)
+	}
+	rpt := New(p, &Options{Minutes: 10})
+	findings := rpt.contentionFindings(rpt.waitGraph(), nil)
+	if len(findings) != 1 {
+		t.Fatalf("got findings %+v, want the wait for the held Mutex only", findings)
+	}
+	if f := findings[0]; len(f.GIDs) != 1 || f.GIDs[0] != 5 {
+		t.Errorf("got finding %q on goroutines %v, want goroutine 5", f.Summary, f.GIDs)
+	}
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// groups returns the goroutine groups of the report, grouped as the
+// options select.
+func (rpt *Report) groups() map[string]dump.TrimedFrame {
+	return rpt.prof.Regroup(rpt.options.Grouping)
+}
+
+// sortedGroups returns the keys of groups, the most goroutines first, then
+// spread on the most instances of a merged dump.
+func sortedGroups(groups map[string]dump.TrimedFrame) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		gi, gj := groups[keys[i]], groups[keys[j]]
+		if gi.Count != gj.Count {
+			return gi.Count > gj.Count
+		}
+		if si, sj := gi.Spread(), gj.Spread(); si != sj {
+			return si > sj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// printGroups lists the goroutine groups, the most goroutines first,
+// with the number of instances of a merged dump they are on.
+func printGroups(w io.Writer, rpt *Report) {
+	groups := rpt.groups()
+	grouping := rpt.options.Grouping.Strategy
+	if grouping == "" {
+		grouping = dump.GroupExact
+	}
+	if rpt.prof.Merged() {
+		fmt.Fprintf(w, "[groups by %s across %d instances, %d goroutines]:\n", grouping, len(rpt.prof.Instances), goroutines(rpt.prof))
+	} else {
+		fmt.Fprintf(w, "[groups by %s]:\n", grouping)
+	}
+	for _, key := range sortedGroups(groups) {
+		tf := groups[key]
+		fmt.Fprintf(w, "%d goroutines", tf.Count)
+		if tf.Instances != nil {
+			fmt.Fprintf(w, " on %d/%d instances", tf.Spread(), len(tf.Instances))
+		}
+		fmt.Fprintf(w, ": [%s] %s\n", tf.Reason, groupCall(&tf.Frame))
+	}
+}
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printHolders lists the locks goroutines wait for, the most waited for
+// first, with the calls waiting for each lock and the goroutines that
+// probably hold it, the most probable first.
+func printHolders(w io.Writer, rpt *Report) {
+	if printInstances(w, rpt, printHolders) {
+		return
+	}
+	contentions := rpt.prof.Contentions(rpt.waitGraph())
+	if len(contentions) == 0 {
+		fmt.Fprintf(w, "no goroutine waits for a lock at a known address\n")
+		return
+	}
+	for _, c := range contentions {
+		fmt.Fprintf(w, "================= %s %#x, %d waiters =================\n", c.Kind, c.Addr, len(c.Waiters))
+		var waiters []dump.Occurrence
+		for _, wait := range c.Waiters {
+			waiters = append(waiters, dump.Occurrence{GID: wait.GID, Stack: wait.Stack + 1})
+		}
+		printCalls(w, rpt, "waiting", waiters)
+		if len(c.Holders) == 0 {
+			fmt.Fprintf(w, "no probable holder found\n")
+			continue
+		}
+		for _, h := range c.Holders {
+			f := rpt.prof.GetFrameByGID(h.GID)
+			if f == nil {
+				continue
+			}
+			stale := ""
+			if h.Stale {
+				stale = " (possibly stale argument)"
+			}
+			fmt.Fprintf(w, "probable holder goroutine %d [%s, %d minutes]%s in\n", f.GID, f.Reason, f.Duration, stale)
+			printCall(w, f, h.Stack)
+		}
+	}
+}
+
+// printCalls counts goroutines by the call they are blocked in, as they
+// mostly are in a few calls, the most common call first.
+func printCalls(w io.Writer, rpt *Report, verb string, occurrences []dump.Occurrence) {
+	var calls []string
+	gids := make(map[string][]int)
+	for _, o := range occurrences {
+		f := rpt.prof.GetFrameByGID(o.GID)
+		call := "unknown"
+		if f != nil && o.Stack < len(f.Stacks) {
+			call = f.Stacks[o.Stack].FuncName
+		}
+		if gids[call] == nil {
+			calls = append(calls, call)
+		}
+		gids[call] = append(gids[call], o.GID)
+	}
+	sort.SliceStable(calls, func(i, j int) bool { return len(gids[calls[i]]) > len(gids[calls[j]]) })
+	for _, call := range calls {
+		fmt.Fprintf(w, "%d %s in %s, goroutines %s\n", len(gids[call]), verb, call, formatGIDs(gids[call]))
+	}
+}
+
+// maxGIDs is the number of goroutines listed by formatGIDs.
+const maxGIDs = 10
+
+// formatGIDs formats a list of goroutine IDs, eliding those after the
+// first maxGIDs.
+func formatGIDs(list []int) string {
+	s := ""
+	for i, gid := range list {
+		if i == maxGIDs {
+			return s + fmt.Sprintf(" and %d more", len(list)-maxGIDs)
+		}
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprint(gid)
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// groupCall returns the function a group of goroutines is blocked in,
+// past the runtime.
+func groupCall(f *dump.Frame) string {
+	for _, s := range f.Stacks {
+		if s.ImportPath != "runtime" {
+			return s.FuncName
+		}
+	}
+	if len(f.Stacks) > 0 {
+		return f.Stacks[0].FuncName
+	}
+	return "unknown"
+}
+
+// instanceReport returns the report on instance i of a merged dump.
+func (rpt *Report) instanceReport(i int) *Report {
+	p := rpt.prof.Instances[i]
+	return &Report{prof: p, series: []*dump.Dump{p}, options: rpt.options}
+}
+
+// instanceName names instance i of a merged dump, numbered from 1.
+func (rpt *Report) instanceName(i int) string {
+	return fmt.Sprintf("instance %d (%s)", i+1, rpt.prof.Instances[i].Source.Name)
+}
+
+// splitInstance splits an argument selecting something on an instance
+// of a merged dump, as value@instance, into the value and the instance,
+// "" if unset.
+func splitInstance(arg string) (value, instance string) {
+	if i := strings.LastIndex(arg, "@"); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+// selectInstances returns the indexes of the instances of a merged dump
+// selected by instance, their number or part of the name of their
+// source, or of every instance if unset.
+func (rpt *Report) selectInstances(instance string) []int {
+	var selected []int
+	for i, p := range rpt.prof.Instances {
+		if instance != "" && instance != strconv.Itoa(i+1) && !strings.Contains(p.Source.Name, instance) {
+			continue
+		}
+		selected = append(selected, i)
+	}
+	return selected
+}
+
+// printInstances prints the report print makes on each instance of a
+// merged dump, under the name of the instance, as goroutine IDs and
+// addresses are only meaningful in the instance they were dumped from.
+// It reports whether the dump is merged.
+func printInstances(w io.Writer, rpt *Report, print func(io.Writer, *Report)) bool {
+	if !rpt.prof.Merged() {
+		return false
+	}
+	for i := range rpt.prof.Instances {
+		fmt.Fprintf(w, "================= %s =================\n", rpt.instanceName(i))
+		print(w, rpt.instanceReport(i))
+	}
+	return true
+}
+
+// printInstanceFrames prints a goroutine of the instances of a merged
+// dump, selected as gid@instance, the instance being its number or part
+// of the name of its source, or as gid on every instance.
+func printInstanceFrames(w io.Writer, rpt *Report, arg string) {
+	gid, instance := splitInstance(arg)
+	id, _ := strconv.Atoi(gid)
+	found := false
+	for _, i := range rpt.selectInstances(instance) {
+		p := rpt.prof.Instances[i]
+		if f := p.GetFrameByGID(id); f != nil {
+			found = true
+			printGoroutine(w, f, fmt.Sprintf("%d@%d (%s)", id, i+1, p.Source.Name))
+		}
+	}
+	if !found {
+		fmt.Fprintf(w, "no such goroutine %s, try another one\n", arg)
+	}
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shippomx/grains/dump"
+)
+
+func TestMergedFindings(t *testing.T) {
+	// Goroutine 12 of the second instance waits on a WaitGroup no
+	// goroutine may call Done on, and the first instance has a goroutine
+	// 12 of its own.
+	const idle = `goroutine 12 [sleep, 30 minutes]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:195 +0x125
+main.idle()
+	/src/app/main.go:8 +0x25
+created by main.main in goroutine 1
+	/src/app/main.go:7 +0x4a
+`
+	const waiting = `goroutine 12 [semacquire, 30 minutes]:
+sync.runtime_Semacquire(0xc000010018?)
+	/usr/local/go/src/runtime/sema.go:62 +0x25
+sync.(*WaitGroup).Wait(0xc000010010)
+	/usr/local/go/src/sync/waitgroup.go:116 +0x48
+main.b()
+	/src/app/main.go:20 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:41 +0x1f6
+`
+	var ps []*dump.Dump
+	for i, text := range []string{idle, waiting} {
+		p := dump.NewDump()
+		if err := p.ParseData(text); err != nil {
+			t.Fatalf("instance %d: %v", i+1, err)
+		}
+		p.Source.Name = []string{"replica-1", "replica-2"}[i]
+		ps = append(ps, p)
+	}
+	rpt := New(dump.Merge(ps), &Options{Minutes: 10})
+
+	findings := rpt.Findings()
+	if len(findings) == 0 {
+		t.Fatal("got no finding on the merged dump")
+	}
+	for _, f := range findings {
+		if f.Instance != 2 || len(f.GIDs) != 1 || f.GIDs[0] != 12 {
+			t.Errorf("got finding %q on goroutines %v of instance %d, want goroutine 12 of instance 2", f.Summary, f.GIDs, f.Instance)
+		}
+	}
+
+	var b bytes.Buffer
+	printFindings(&b, rpt)
+	if !strings.Contains(b.String(), "on instance 2 (replica-2)") || !strings.Contains(b.String(), "main.b") {
+		t.Errorf("findings do not name the instance and its call:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := rpt.writeProto(&b); err != nil {
+		t.Fatal(err)
+	}
+	p := dump.NewDump()
+	if err := p.Parse(&b); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Findings) != len(findings) || p.Findings[0].Instance != 2 || p.Findings[0].Summary != findings[0].Summary {
+		t.Errorf("got saved findings %+v, want %+v", p.Findings, findings)
+	}
+
+	b.Reset()
+	printTrimed(&b, rpt)
+	for _, head := range []string{"{gid: 12@1,", "{gid: 12@2,"} {
+		if !strings.Contains(b.String(), head) {
+			t.Errorf("trimmed groups lack head %s:\n%s", head, b.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/shippomx/grains/dump"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -13,12 +14,20 @@ import (
 const (
 	Text = iota
 	Raw
+	Proto
 )
 
 // Options are the formatting and filtering options used to generate a
 // dump.
 type Options struct {
 	OutputFormat int
+
+	Depth    int // maximum depth of trees, 0 for no limit
+	Snapshot int // snapshot to report on, from 1, 0 for the latest
+	Minutes  int // minutes goroutines are blocked for to be reported as leaked
+
+	Grouping   dump.Grouping // how trim and dump group goroutines
+	Similarity float64       // similarity of the stacks of clustered groups, from 0 to 1
 }
 
 // Generate generates a report as directed by the Report.
@@ -30,6 +39,30 @@ func Generate(w io.Writer, rpt *Report, cmd []string) (err error) {
 		printFrame(w, rpt, cmd[1])
 	case "dump":
 		saveTrimed(w, rpt)
+	case "tree":
+		printTree(w, rpt, cmd[1])
+	case "snapshots":
+		printSnapshots(w, rpt)
+	case "crash":
+		printCrash(w, rpt)
+	case "who":
+		printWho(w, rpt, cmd[1])
+	case "holders":
+		printHolders(w, rpt)
+	case "channels":
+		printChannels(w, rpt)
+	case "waits":
+		printWaits(w, rpt)
+	case "findings":
+		printFindings(w, rpt)
+	case "diff":
+		printDiff(w, rpt)
+	case "trend":
+		printTrend(w, rpt)
+	case "cluster":
+		printClusters(w, rpt)
+	case "proto":
+		err = rpt.writeProto(w)
 	}
 
 	return
@@ -39,7 +72,12 @@ func Generate(w io.Writer, rpt *Report, cmd []string) (err error) {
 // report from a dump.
 type Report struct {
 	prof    *dump.Dump
+	series  []*dump.Dump // snapshots prof was selected from
+	current int          // index of prof in series
+	base    *dump.Dump   // dump the diff report compares prof with, if set
 	options *Options
+
+	graph *dump.WaitGraph // wait graph of prof, built once needed
 }
 
 // New builds a new report indexing the sample values interpreting the
@@ -48,67 +86,90 @@ func New(prof *dump.Dump, o *Options) *Report {
 	// Trim
 	return &Report{
 		prof:    prof.Duplicated(),
+		series:  []*dump.Dump{prof},
 		options: o,
 	}
 }
 
-func hasDeadLock(f1, f2 *dump.Frame) bool {
-	if len(f1.LockHolders) < 1 || len(f2.LockHolders) < 1 {
-		return false
-	}
-	for idxi, hi1 := range f1.LockHolders {
-		for idxj, hj1 := range f2.LockHolders {
-			if hi1 == hj1 { // find the first equal pair
-				for j := idxj + 1; j < len(f2.LockHolders); j++ {
-					thj := f2.LockHolders[j]
-					for i := idxi - 1; i > 0; i-- {
-						thi := f1.LockHolders[i]
-						if thi == thj { // find the second equal pair
-							return true
-						}
-					}
-				}
-			}
-		}
+// waitGraph returns the wait graph of the reported dump, built once for
+// the findings and lock reports that need it.
+func (rpt *Report) waitGraph() *dump.WaitGraph {
+	if rpt.graph == nil {
+		rpt.graph = rpt.prof.WaitGraph()
+	}
+	return rpt.graph
+}
+
+// NewSeries builds a new report on the snapshot of series selected by
+// the options, in a series of snapshots ordered as they were read.
+func NewSeries(series []*dump.Dump, o *Options) (*Report, error) {
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no snapshot to report on")
+	}
+	if err := o.Grouping.Check(); err != nil {
+		return nil, err
+	}
+	if err := dump.CheckSimilarity(o.Similarity); err != nil {
+		return nil, err
+	}
+	i := o.Snapshot
+	if i == 0 {
+		i = len(series)
 	}
-	return false
+	if i < 0 || i > len(series) {
+		return nil, fmt.Errorf("no snapshot %d, there are %d snapshots", o.Snapshot, len(series))
+	}
+	rpt := New(series[i-1], o)
+	rpt.series, rpt.current = series, i-1
+	return rpt, nil
 }
 
 func trimStacks(w io.Writer, rpt *Report) {
 	fmt.Fprintf(w, "================= Summary =================\n")
 	fmt.Fprint(w, "[blocked goroutine types]:\n")
-	for reason, cnt := range rpt.prof.Surmary {
-		fmt.Fprintf(w, "%s: %d\n", reason, cnt)
-		if cnt > 1 {
-			frames := rpt.prof.GetFramesByReason(reason, 0)
-			for i := 0; i < len(frames); i++ {
-				for j := i + 1; j < len(frames); j++ {
-					if hasDeadLock(frames[i], frames[j]) {
-						fmt.Fprintf(w, "================= WARNING DEAD LOCK %s =================\n", frames[i].Reason)
-						fmt.Fprintf(w, "goroutine %d has surspicous DEAD LOCK with goroutine %d\n", frames[i].GID, frames[j].GID)
-						fmt.Fprintf(w, "LockHolders of goroutine %d: %v\n", frames[i].GID, frames[i].LockHolders)
-						fmt.Fprintf(w, "LockHolders of goroutine %d: %v\n", frames[j].GID, frames[j].LockHolders)
-					}
-				}
-			}
+	reasons := make([]string, 0, len(rpt.prof.Surmary))
+	for reason := range rpt.prof.Surmary {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		ci, cj := rpt.prof.Surmary[reasons[i]], rpt.prof.Surmary[reasons[j]]
+		if ci != cj {
+			return ci > cj
 		}
+		return reasons[i] < reasons[j]
+	})
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "%s: %d\n", reason, rpt.prof.Surmary[reason])
 	}
+	printGroups(w, rpt)
+	printFindingsSummary(w, rpt)
+	printRWMutexes(w, rpt)
 	return
 }
 
 func printFrame(w io.Writer, rpt *Report, gid string) {
+	if rpt.prof.Merged() {
+		printInstanceFrames(w, rpt, gid)
+		return
+	}
 	id, _ := strconv.Atoi(gid)
 	f := rpt.prof.GetFrameByGID(id)
 	if f == nil {
 		fmt.Fprintf(w, "no such goroutine %s, try another one\n", gid)
 		return
 	}
+	printGoroutine(w, f, gid)
+}
+
+// printGoroutine prints goroutine f, named gid.
+func printGoroutine(w io.Writer, f *dump.Frame, gid string) {
 	fmt.Fprintf(w, "================= goroutine %s start =================\n", gid)
 
 	fmt.Fprintf(w, "goroutine %d [%s, %d minutes]:\n", f.GID, f.Reason, f.Duration)
 	for _, stack := range f.Stacks {
 		fmt.Fprintf(w, "%s(%s)\n\t%s\n", stack.FuncName, stack.Params, stack.Location)
 	}
+	printCreator(w, f.Creator)
 
 	fmt.Fprintf(w, "================= goroutine %s end =================\n", gid)
 	return
@@ -126,12 +187,31 @@ func saveTrimed(w io.Writer, rpt *Report) {
 }
 
 func printTrimed(w io.Writer, rpt *Report) {
-	for reason, frame := range rpt.prof.TrimedFrames {
+	printFindingsSummary(w, rpt)
+	groups := rpt.groups()
+	for _, reason := range sortedGroups(groups) {
+		frame := groups[reason]
 		fmt.Fprintf(w, "[%s]:\n", reason)
 		if frame.LockInfo.Stack != nil {
-			fmt.Fprint(w, "[LockType:%s, FuncName: %s, Location: %s]\n", frame.LockInfo.LockType, frame.LockInfo.FuncName, frame.Location)
+			fmt.Fprintf(w, "[LockType:%s, FuncName: %s, Location: %s]\n", frame.LockInfo.LockType, frame.LockInfo.FuncName, frame.Location)
+		}
+		fmt.Fprintf(w, "[count: %d]\n", frame.Count)
+		if frame.Instances != nil {
+			fmt.Fprintf(w, "[instances: %d/%d]\n", frame.Spread(), len(frame.Instances))
+		}
+		labels := make([]string, 0, len(frame.Labels))
+		for k := range frame.Labels {
+			labels = append(labels, k)
+		}
+		sort.Strings(labels)
+		for _, k := range labels {
+			fmt.Fprintf(w, "{label: %s=%s}, ", k, frame.Labels[k])
 		}
 		for _, head := range frame.Heads {
+			if head.Instance > 0 {
+				fmt.Fprintf(w, "{gid: %d@%d, duration: %d min}, ", head.GID, head.Instance, head.Duration)
+				continue
+			}
 			fmt.Fprintf(w, "{gid: %d, duration: %d min}, ", head.GID, head.Duration)
 		}
 
@@ -139,7 +219,19 @@ func printTrimed(w io.Writer, rpt *Report) {
 		for _, stack := range frame.Stacks {
 			fmt.Fprintf(w, "\t%s %s\n%s\n", stack.FuncName, stack.Params, stack.Location)
 		}
+		printCreator(w, frame.Creator)
 
 		fmt.Fprintf(w, "\n")
 	}
 }
+
+func printCreator(w io.Writer, c *dump.Creator) {
+	if c == nil {
+		return
+	}
+	if c.GID > 0 {
+		fmt.Fprintf(w, "created by %s in goroutine %d\n\t%s\n", c.FuncName, c.GID, c.Location)
+		return
+	}
+	fmt.Fprintf(w, "created by %s\n\t%s\n", c.FuncName, c.Location)
+}
@@ -0,0 +1,28 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shippomx/grains/dump"
+)
+
+func TestPrintTrimedLabels(t *testing.T) {
+	p := dump.NewDump()
+	if err := p.ParseData("goroutine 5 [chan receive, 3 minutes]:\nmain.worker()\n\t/src/app/main.go:12 +0x45\n"); err != nil {
+		t.Fatal(err)
+	}
+	for key, tf := range p.TrimedFrames {
+		tf.Labels = map[string]string{"route": "/users", "method": "GET", "tenant": "acme", "handler": "users"}
+		p.TrimedFrames[key] = tf
+	}
+	const want = "{label: handler=users}, {label: method=GET}, {label: route=/users}, {label: tenant=acme}, {gid: 5,"
+	for i := 0; i < 10; i++ {
+		var b bytes.Buffer
+		printTrimed(&b, New(p, &Options{}))
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("labels are not sorted:\n%s", b.String())
+		}
+	}
+}
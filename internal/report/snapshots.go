@@ -0,0 +1,57 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printSnapshots lists the snapshots of the report, marking the one
+// reported on.
+func printSnapshots(w io.Writer, rpt *Report) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t#\tsource\tprocess\ttime\tgoroutines")
+	for i, p := range rpt.series {
+		mark := ""
+		if i == rpt.current {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\n", mark, i+1, p.Source.Name, process(p.Source), snapshotTime(p.Source), goroutines(p))
+	}
+	tw.Flush()
+}
+
+// process describes the process dumped in a snapshot.
+func process(src dump.Source) string {
+	var s []string
+	if src.Process != "" {
+		s = append(s, src.Process)
+	}
+	if src.PID > 0 {
+		s = append(s, fmt.Sprintf("pid %d", src.PID))
+	}
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, " ")
+}
+
+func snapshotTime(src dump.Source) string {
+	if src.Time.IsZero() {
+		return "-"
+	}
+	return src.Time.Format(time.RFC3339)
+}
+
+// goroutines returns the number of goroutines of a dump.
+func goroutines(p *dump.Dump) int64 {
+	var n int64
+	for _, cnt := range p.Surmary {
+		n += cnt
+	}
+	return n
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// spawnNode is a set of goroutines started by the same go statement
+// from the goroutines of its parent node.
+type spawnNode struct {
+	creator  *dump.Creator // go statement, nil for goroutines without one
+	gids     []int
+	children []*spawnNode
+}
+
+// label returns a single-line description of the node.
+func (n *spawnNode) label(p *dump.Dump) string {
+	var site string
+	if n.creator == nil {
+		site = "(no creator)"
+	} else {
+		site = fmt.Sprintf("%s %s", n.creator.FuncName, n.creator.Location)
+	}
+	if len(n.gids) == 1 {
+		f := p.GetFrameByGID(n.gids[0])
+		return fmt.Sprintf("goroutine %d [%s] %s", f.GID, f.Reason, site)
+	}
+	return fmt.Sprintf("%d goroutines %s", len(n.gids), site)
+}
+
+// groupBySpawnSite collapses goroutines started by the same go
+// statement into nodes, ordered by decreasing count.
+func groupBySpawnSite(p *dump.Dump, gids []int) (nodes []*spawnNode) {
+	sites := make(map[string]*spawnNode)
+	for _, gid := range gids {
+		f := p.GetFrameByGID(gid)
+		if f == nil {
+			continue
+		}
+		var key string
+		if f.Creator != nil {
+			key = f.Creator.FuncName + " " + f.Creator.Location
+		}
+		n, ok := sites[key]
+		if !ok {
+			n = &spawnNode{creator: f.Creator}
+			sites[key] = n
+			nodes = append(nodes, n)
+		}
+		n.gids = append(n.gids, gid)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return len(nodes[i].gids) > len(nodes[j].gids)
+	})
+	return
+}
+
+// expand adds the goroutines started by the goroutines of n to the
+// tree, down to depth levels below n if depth is positive. Goroutines
+// already in the tree are skipped, as IDs may be reused in a dump.
+func (n *spawnNode) expand(p *dump.Dump, depth int, seen map[int]bool) {
+	if depth == 1 {
+		return
+	}
+	var children []int
+	for _, gid := range n.gids {
+		for _, child := range p.Children[gid] {
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
+		}
+	}
+	sort.Ints(children)
+	n.children = groupBySpawnSite(p, children)
+	for _, child := range n.children {
+		child.expand(p, depth-1, seen)
+	}
+}
+
+// spawnTree returns the roots of the spawn tree of the dump. If focus
+// is set, the tree is rooted at that goroutine, otherwise at every
+// goroutine whose parent is not part of the dump.
+func spawnTree(p *dump.Dump, focus string, depth int) ([]*spawnNode, error) {
+	var roots []*spawnNode
+	if focus != "" {
+		gid, err := strconv.Atoi(focus)
+		if err != nil || p.GetFrameByGID(gid) == nil {
+			return nil, fmt.Errorf("no such goroutine %s, try another one", focus)
+		}
+		roots = []*spawnNode{{creator: p.GetCreatorByGID(gid), gids: []int{gid}}}
+	} else {
+		var gids []int
+		for gid := range p.Goroutines {
+			if c := p.GetCreatorByGID(gid); c != nil && c.GID > 0 {
+				if _, ok := p.Goroutines[c.GID]; ok {
+					continue
+				}
+			}
+			gids = append(gids, gid)
+		}
+		sort.Ints(gids)
+		roots = groupBySpawnSite(p, gids)
+	}
+	seen := make(map[int]bool)
+	for _, root := range roots {
+		for _, gid := range root.gids {
+			seen[gid] = true
+		}
+	}
+	for _, root := range roots {
+		root.expand(p, depth, seen)
+	}
+	return roots, nil
+}
+
+// printTree prints the spawn tree rooted at goroutine gid, or at every
+// goroutine without a parent for "all". On a merged dump, the tree is
+// printed for the instance selected as gid@instance, or for every
+// instance the goroutine is on.
+func printTree(w io.Writer, rpt *Report, gid string) {
+	if rpt.prof.Merged() {
+		gid, instance := splitInstance(gid)
+		found := false
+		for _, i := range rpt.selectInstances(instance) {
+			if id, err := strconv.Atoi(gid); gid != "all" && (err != nil || rpt.prof.Instances[i].GetFrameByGID(id) == nil) {
+				continue
+			}
+			found = true
+			fmt.Fprintf(w, "================= %s =================\n", rpt.instanceName(i))
+			printTree(w, rpt.instanceReport(i), gid)
+		}
+		if !found {
+			fmt.Fprintf(w, "no such goroutine %s, try another one\n", gid)
+		}
+		return
+	}
+	if gid == "all" {
+		gid = ""
+	}
+	roots, err := spawnTree(rpt.prof, gid, rpt.options.Depth)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintf(w, "================= Spawn Tree =================\n")
+	for _, root := range roots {
+		fmt.Fprintln(w, root.label(rpt.prof))
+		printSpawnNodes(w, rpt.prof, root.children, "")
+	}
+}
+
+func printSpawnNodes(w io.Writer, p *dump.Dump, nodes []*spawnNode, indent string) {
+	for i, n := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintln(w, indent+branch+n.label(p))
+		printSpawnNodes(w, p, n.children, indent+next)
+	}
+}
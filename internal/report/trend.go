@@ -0,0 +1,94 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// printTrend tracks the goroutine groups over the snapshots of the
+// report, and lists as leak suspects the groups growing and those whose
+// oldest goroutine keeps waiting, then counts the other groups.
+func printTrend(w io.Writer, rpt *Report) {
+	if len(rpt.series) < 2 {
+		fmt.Fprintf(w, "no trend in a single snapshot, read several dumps or a file of snapshots\n")
+		return
+	}
+	t := dump.NewTrend(rpt.series)
+	unit := "snapshot"
+	span := fmt.Sprintf("%d snapshots", len(rpt.series))
+	if t.Timed {
+		unit = "minute"
+		span += fmt.Sprintf(" over %.0f minutes", t.Times[len(t.Times)-1])
+	}
+	fmt.Fprintf(w, "================= Trend of %s =================\n", span)
+
+	var stable, shrinking int
+	suspects := 0
+	for _, g := range t.Groups {
+		if !g.LeakSuspect() {
+			if g.Counts[len(g.Counts)-1] < g.Counts[0] {
+				shrinking++
+			} else {
+				stable++
+			}
+			continue
+		}
+		if suspects == 0 {
+			fmt.Fprint(w, "[leak suspects]:\n")
+		}
+		suspects++
+		kind := "aging"
+		if g.Growing() {
+			kind = "growing"
+		}
+		last := len(g.Counts) - 1
+		fmt.Fprintf(w, "%s %+.2f goroutines/%s, %d -> %d goroutines, longest wait %s -> %s minutes: [%s] %s\n",
+			kind, g.Slope, unit, g.Counts[0], g.Counts[last], firstPresent(g.Minutes), lastPresent(g.Minutes), g.Frame.Reason, groupCall(&g.Frame.Frame))
+		fmt.Fprintf(w, "\tgoroutines: %s\n", joinInts(g.Counts))
+		fmt.Fprintf(w, "\tlongest wait: %s\n", joinInts(g.Minutes))
+	}
+	if suspects == 0 {
+		fmt.Fprint(w, "no group grows or keeps waiting\n")
+	}
+	fmt.Fprintf(w, "%d other groups, %d shrinking\n", stable+shrinking, shrinking)
+}
+
+// firstPresent formats the first of values not missing, or "absent" if
+// all are.
+func firstPresent(values []int) string {
+	for _, v := range values {
+		if v >= 0 {
+			return strconv.Itoa(v)
+		}
+	}
+	return "absent"
+}
+
+// lastPresent formats the last of values not missing, or "absent" if all
+// are.
+func lastPresent(values []int) string {
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] >= 0 {
+			return strconv.Itoa(values[i])
+		}
+	}
+	return "absent"
+}
+
+// joinInts formats values, - standing for the negative ones, which are
+// missing.
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		if v < 0 {
+			s[i] = "-"
+			continue
+		}
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, " ")
+}
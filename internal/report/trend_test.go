@@ -0,0 +1,38 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shippomx/grains/dump"
+)
+
+func TestPrintTrendAbsentGroup(t *testing.T) {
+	// The pollers are not in the first snapshot, and keep growing.
+	start := time.Date(2023, 9, 14, 8, 0, 0, 0, time.UTC)
+	var series []*dump.Dump
+	for i, text := range []string{
+		"goroutine 1 [running]:\nmain.main()\n\t/src/app/main.go:7 +0x1d\n",
+		"goroutine 5 [chan receive, 2 minutes]:\nmain.poll()\n\t/src/app/poll.go:20 +0x25\n",
+		"goroutine 5 [chan receive, 4 minutes]:\nmain.poll()\n\t/src/app/poll.go:20 +0x25\n\n" +
+			"goroutine 6 [chan receive, 1 minutes]:\nmain.poll()\n\t/src/app/poll.go:20 +0x25\n",
+	} {
+		p := dump.NewDump()
+		if err := p.ParseData(text); err != nil {
+			t.Fatal(err)
+		}
+		p.Source.Time = start.Add(time.Duration(2*i) * time.Minute)
+		series = append(series, p)
+	}
+	rpt, err := NewSeries(series, &Options{Similarity: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	printTrend(&b, rpt)
+	if want := "0 -> 2 goroutines, longest wait 2 -> 4 minutes"; !strings.Contains(b.String(), want) {
+		t.Errorf("trend lacks %q:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,246 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/shippomx/grains/dump"
+)
+
+// syncWaitFuncs maps the methods waiting for other goroutines to the
+// object they wait on, and to what those goroutines must call to end the
+// wait.
+var syncWaitFuncs = map[string]struct{ object, release string }{
+	"sync.(*WaitGroup).Wait":                          {"sync.WaitGroup", "Done"},
+	"sync.(*Cond).Wait":                               {"sync.Cond", "Signal or Broadcast"},
+	"golang.org/x/sync/semaphore.(*Weighted).Acquire": {"semaphore.Weighted", "Release"},
+	"golang.org/x/sync/errgroup.(*Group).Wait":        {"errgroup.Group", "return from Go"},
+}
+
+// condNotify is the offset of the notify list passed to
+// runtime_notifyListWait in a sync.Cond.
+const condNotify = 16
+
+// syncWait is a goroutine waiting on a WaitGroup, a Cond, a semaphore or
+// an errgroup for other goroutines.
+type syncWait struct {
+	object  string // type of the object waited on
+	release string // what other goroutines call to end the wait
+	gid     int
+	stack   int    // index of the call waiting in the stacks of the goroutine
+	addr    uint64 // address of the object, or of its owner if not printed
+	owner   uint64 // address of the object whose method waits, 0 if unknown
+}
+
+// syncObject is an object goroutines wait on. Objects at an unknown
+// address are told apart by their waiter, as they may be as many as
+// their waiters.
+type syncObject struct {
+	object string
+	addr   uint64
+	gid    int // goroutine waiting on an object at an unknown address
+}
+
+func (o syncObject) String() string {
+	if o.addr == 0 {
+		return fmt.Sprintf("%s at an unknown address, waited on by goroutine %d", o.object, o.gid)
+	}
+	return fmt.Sprintf("%s %#x", o.object, o.addr)
+}
+
+// methodName returns the name of a method as in syncWaitFuncs, without
+// the vendor prefix of its package.
+func methodName(s *dump.Stack) string {
+	if !s.PointerReceiver || s.Closure != "" {
+		return ""
+	}
+	return s.ImportPath + ".(*" + s.Receiver + ")." + s.Method
+}
+
+// decodeSyncWait returns what f waits on for other goroutines, or nil.
+// errgroup.Group.Wait waits on a WaitGroup, so a wait is told by the
+// outermost of the calls waiting.
+func decodeSyncWait(f *dump.Frame) *syncWait {
+	if f.Reason == "running" || f.Reason == "runnable" {
+		return nil
+	}
+	i := 0
+	var notify uint64
+	for ; i < len(f.Stacks); i++ {
+		s := &f.Stacks[i]
+		if s.Method == "runtime_notifyListWait" && len(s.Args) > 0 && !s.Args[0].Unsure {
+			notify = s.Args[0].Value
+		}
+		if s.ImportPath != "runtime" && !strings.HasPrefix(s.Method, "runtime_") {
+			break
+		}
+	}
+	var w *syncWait
+	for ; i < len(f.Stacks); i++ {
+		fn, ok := syncWaitFuncs[methodName(&f.Stacks[i])]
+		if !ok {
+			break
+		}
+		w = &syncWait{object: fn.object, release: fn.release, gid: f.GID, stack: i}
+		w.addr, _ = f.Stacks[i].Object()
+		// The notify list of a Cond follows its Locker, which takes 16
+		// bytes on 64-bit platforms.
+		if w.addr == 0 && fn.object == "sync.Cond" && notify > condNotify {
+			w.addr = notify - condNotify
+		}
+	}
+	if w != nil && i < len(f.Stacks) {
+		w.owner, _ = f.Stacks[i].Object()
+	}
+	if w != nil && w.addr == 0 {
+		w.addr = w.owner
+	}
+	return w
+}
+
+// releasers returns the goroutines that may end the waits on an object:
+// the goroutines the waiters started, and the goroutines passed the
+// object or the owners of the waits, other than the waiters, looked up
+// in the words passed to the calls of the dump, as indexed by
+// dump.PassedWords.
+func releasers(p *dump.Dump, passed map[uint64][]dump.Occurrence, waits []*syncWait) []dump.Occurrence {
+	waiting := make(map[int]bool)
+	addrs := make(map[uint64]bool)
+	for _, w := range waits {
+		waiting[w.gid] = true
+		for _, addr := range []uint64{w.addr, w.owner} {
+			if addr != 0 {
+				addrs[addr] = true
+			}
+		}
+	}
+	seen := make(map[int]bool)
+	var occurrences []dump.Occurrence
+	add := func(gid, stack int) {
+		if !waiting[gid] && !seen[gid] {
+			seen[gid] = true
+			occurrences = append(occurrences, dump.Occurrence{GID: gid, Stack: stack})
+		}
+	}
+	for _, w := range waits {
+		for _, child := range p.GetChildrenByGID(w.gid) {
+			add(child.GID, entryCall(child))
+		}
+	}
+	// The outermost call of each goroutine passed one of the addresses.
+	calls := make(map[int]int)
+	for addr := range addrs {
+		for _, o := range passed[addr] {
+			if i, ok := calls[o.GID]; !ok || o.Stack > i {
+				calls[o.GID] = o.Stack
+			}
+		}
+	}
+	for gid, i := range calls {
+		add(gid, i)
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].GID < occurrences[j].GID })
+	return occurrences
+}
+
+// waitedFor reports whether all the waits lasted at least minutes.
+func waitedFor(p *dump.Dump, waits []*syncWait, minutes int) bool {
+	for _, w := range waits {
+		if f := p.GetFrameByGID(w.gid); f == nil || f.Duration < minutes {
+			return false
+		}
+	}
+	return true
+}
+
+// entryCall returns the index of the function a goroutine was started
+// with, past the runtime functions ending it.
+func entryCall(f *dump.Frame) int {
+	for i := len(f.Stacks) - 1; i > 0; i-- {
+		if f.Stacks[i].ImportPath != "runtime" {
+			return i
+		}
+	}
+	return 0
+}
+
+// syncWaits groups the goroutines waiting on a WaitGroup, a Cond, a
+// semaphore or an errgroup by the object waited on, ordered by type and
+// address, and the waits by goroutine.
+func syncWaits(p *dump.Dump) ([]syncObject, map[syncObject][]*syncWait) {
+	objects := make(map[syncObject][]*syncWait)
+	var keys []syncObject
+	for _, f := range p.RawFrames {
+		if wait := decodeSyncWait(f); wait != nil {
+			key := syncObject{object: wait.object, addr: wait.addr}
+			if key.addr == 0 {
+				key.gid = wait.gid
+			}
+			if objects[key] == nil {
+				keys = append(keys, key)
+			}
+			objects[key] = append(objects[key], wait)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].object != keys[j].object {
+			return keys[i].object < keys[j].object
+		}
+		if keys[i].addr != keys[j].addr {
+			return keys[i].addr < keys[j].addr
+		}
+		return keys[i].gid < keys[j].gid
+	})
+	for _, waits := range objects {
+		sort.Slice(waits, func(i, j int) bool { return waits[i].gid < waits[j].gid })
+	}
+	return keys, objects
+}
+
+// printWaits groups the goroutines waiting on a WaitGroup, a Cond, a
+// semaphore or an errgroup by the object waited on, with the goroutines
+// that may end their wait, and warns of the long waits no goroutine was
+// found to end.
+func printWaits(w io.Writer, rpt *Report) {
+	if printInstances(w, rpt, printWaits) {
+		return
+	}
+	keys, objects := syncWaits(rpt.prof)
+	if len(keys) == 0 {
+		fmt.Fprintf(w, "no goroutine waits on a WaitGroup, a Cond, a semaphore or an errgroup\n")
+		return
+	}
+	passed := rpt.prof.PassedWords()
+	var orphans []syncObject
+	for _, key := range keys {
+		waits := objects[key]
+		fmt.Fprintf(w, "================= %s, %d waiters =================\n", key, len(waits))
+		var waiters []dump.Occurrence
+		for _, wait := range waits {
+			waiters = append(waiters, dump.Occurrence{GID: wait.gid, Stack: wait.stack + 1})
+		}
+		printCalls(w, rpt, "waiting", waiters)
+		rs := releasers(rpt.prof, passed, waits)
+		if len(rs) == 0 {
+			fmt.Fprintf(w, "no goroutine found to %s\n", waits[0].release)
+			if waitedFor(rpt.prof, waits, rpt.options.Minutes) {
+				orphans = append(orphans, key)
+			}
+			continue
+		}
+		printCalls(w, rpt, "which may "+waits[0].release, rs)
+	}
+
+	for _, key := range orphans {
+		waits := objects[key]
+		fmt.Fprintf(w, "================= WARNING %s WAIT =================\n", strings.ToUpper(key.object))
+		fmt.Fprintf(w, "no goroutine found to %s %s, waited on for %d minutes or more by\n", waits[0].release, key, rpt.options.Minutes)
+		for _, wait := range waits {
+			f := rpt.prof.GetFrameByGID(wait.gid)
+			fmt.Fprintf(w, "goroutine %d [%s, %d minutes] in\n", f.GID, f.Reason, f.Duration)
+			printCall(w, f, wait.stack+1)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/shippomx/grains/dump"
+)
+
+func TestSyncWaitsUnknownAddress(t *testing.T) {
+	// Goroutines 10 and 12 wait on WaitGroups at unknown addresses, and
+	// only goroutine 10 started a goroutine that may call Done.
+	const text = `goroutine 10 [semacquire, 30 minutes]:
+sync.runtime_Semacquire(0xc000010008?)
+	/usr/local/go/src/runtime/sema.go:62 +0x25
+sync.(*WaitGroup).Wait(0xc000010000?)
+	/usr/local/go/src/sync/waitgroup.go:116 +0x48
+main.a()
+	/src/app/main.go:10 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:40 +0x1f6
+
+goroutine 11 [sleep, 30 minutes]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:195 +0x125
+main.a.func1()
+	/src/app/main.go:8 +0x25
+created by main.a in goroutine 10
+	/src/app/main.go:7 +0x4a
+
+goroutine 12 [semacquire, 30 minutes]:
+sync.runtime_Semacquire(0xc000010018?)
+	/usr/local/go/src/runtime/sema.go:62 +0x25
+sync.(*WaitGroup).Wait(0xc000010010?)
+	/usr/local/go/src/sync/waitgroup.go:116 +0x48
+main.b()
+	/src/app/main.go:20 +0x85
+created by main.main in goroutine 1
+	/src/app/main.go:41 +0x1f6
+`
+	p := dump.NewDump()
+	if err := p.ParseData(text); err != nil {
+		t.Fatal(err)
+	}
+	keys, objects := syncWaits(p)
+	passed := p.PassedWords()
+	if len(keys) != 2 {
+		t.Fatalf("got objects %v, want one per waiter", keys)
+	}
+	for _, tc := range []struct {
+		gid       int
+		releasers int
+	}{
+		{10, 1},
+		{12, 0},
+	} {
+		key := syncObject{object: "sync.WaitGroup", gid: tc.gid}
+		waits := objects[key]
+		if len(waits) != 1 {
+			t.Errorf("%s: got %d waiters, want 1", key, len(waits))
+			continue
+		}
+		if got := releasers(p, passed, waits); len(got) != tc.releasers {
+			t.Errorf("%s: got releasers %v, want %d", key, got, tc.releasers)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// printWho lists the goroutines that were passed the object at addr, as
+// the receiver of a method or the channel or map of a runtime function,
+// with the calls they passed it to. On a merged dump, the object is
+// looked up on the instance selected as addr@instance, or on every
+// instance.
+func printWho(w io.Writer, rpt *Report, addr string) {
+	addr, instance := splitInstance(addr)
+	a, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(addr), "0x"), 16, 64)
+	if err != nil {
+		fmt.Fprintf(w, "bad address %s, try a hexadecimal one such as 0xc000010000\n", addr)
+		return
+	}
+	found := false
+	if rpt.prof.Merged() {
+		for _, i := range rpt.selectInstances(instance) {
+			if len(rpt.prof.Instances[i].GetOccurrencesByAddr(a)) > 0 {
+				found = true
+				fmt.Fprintf(w, "================= %s =================\n", rpt.instanceName(i))
+				printOccurrences(w, rpt.instanceReport(i), a)
+			}
+		}
+	} else {
+		found = printOccurrences(w, rpt, a)
+	}
+	if !found {
+		fmt.Fprintf(w, "no goroutine was passed %#x, try another one\n", a)
+	}
+}
+
+// printOccurrences prints the calls the object at addr was passed to, and
+// reports whether there are any.
+func printOccurrences(w io.Writer, rpt *Report, a uint64) bool {
+	occurrences := rpt.prof.GetOccurrencesByAddr(a)
+	if len(occurrences) == 0 {
+		return false
+	}
+	fmt.Fprintf(w, "================= object %#x =================\n", a)
+	gid := 0
+	for _, o := range occurrences {
+		f := rpt.prof.GetFrameByGID(o.GID)
+		if f == nil {
+			continue
+		}
+		if o.GID != gid {
+			gid = o.GID
+			fmt.Fprintf(w, "goroutine %d [%s, %d minutes]:\n", f.GID, f.Reason, f.Duration)
+		}
+		stack := f.Stacks[o.Stack]
+		fmt.Fprintf(w, "\t%s(%s)\n\t\t%s\n", stack.FuncName, stack.Params, stack.Location)
+		if o.Stale {
+			fmt.Fprintf(w, "\t\t(possibly stale: the address was passed in a register)\n")
+		}
+	}
+	return true
+}